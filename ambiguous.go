@@ -0,0 +1,54 @@
+package slogenv
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// WithWarnAmbiguousShortNames writes a warning to stderr, once for each filter, when a package filter given by
+// name, such as config=debug, matches seen packages with different import paths, see SeenPackages, so a filter
+// meant for one of them can be given its full import path instead. Packages are checked when they are first
+// seen and whenever the levels change at runtime.
+func WithWarnAmbiguousShortNames(warn bool) Opt {
+	return func(cfg *config) {
+		cfg.warnAmbiguousShortNames = warn
+	}
+}
+
+// ambiguousNames warns about package filters matching seen packages with different import paths.
+type ambiguousNames struct {
+	out    io.Writer
+	warned sync.Map
+}
+
+// check writes a warning for each package filter in state, which hasn't been warned about yet, whose name
+// matches seen packages with different import paths.
+func (a *ambiguousNames) check(h *Handler, state *levelState) {
+	paths := make(map[string][]string)
+	for path, resolved := range h.seenPackages() {
+		paths[resolved.pkg] = append(paths[resolved.pkg], path)
+	}
+
+	for _, key := range sortedPackages(state.perPackageLevel) {
+		if !isShortNameKey(key) || len(paths[key]) < 2 {
+			continue
+		}
+		if _, warned := a.warned.LoadOrStore(key, struct{}{}); warned {
+			continue
+		}
+		sort.Strings(paths[key])
+		fmt.Fprintf(a.out, "slog-env: filter %s matches packages %s, use a full import path to target one of them\n",
+			formatRule(key, "", state.perPackageLevel[key]), strings.Join(paths[key], ", "))
+	}
+}
+
+// isShortNameKey reports whether a filter key is a package given by name rather than by path or pattern.
+func isShortNameKey(key string) bool {
+	if strings.HasPrefix(key, groupPrefix) || isTargetKey(key) || isAttrKey(key) || isGlobKey(key) {
+		return false
+	}
+	return !strings.ContainsAny(key, "/*")
+}
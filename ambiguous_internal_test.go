@@ -0,0 +1,57 @@
+package slogenv
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	shadow "github.com/cbrewster/slog-env/internal/shadow/testpackage"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+)
+
+const ambiguousWarning = "slog-env: filter testpackage=DEBUG matches packages " +
+	"github.com/cbrewster/slog-env/internal/shadow/testpackage, github.com/cbrewster/slog-env/internal/testpackage, " +
+	"use a full import path to target one of them\n"
+
+// TestWarnAmbiguousShortNames tests warning once about a filter naming packages with different import paths.
+func TestWarnAmbiguousShortNames(t *testing.T) {
+	t.Setenv("GO_LOG", "info,testpackage=debug")
+
+	h := NewHandler(discardHandler{}, WithWarnAmbiguousShortNames(true))
+	var out bytes.Buffer
+	h.ambiguous.out = &out
+
+	logger := slog.New(h)
+	testpackage.LogSomething(logger, slog.LevelDebug, "debug")
+	assert.Empty(t, out.String())
+
+	shadow.LogSomething(logger, slog.LevelDebug, "debug")
+	assert.Equal(t, ambiguousWarning, out.String())
+
+	testpackage.LogContext(context.Background(), logger, slog.LevelDebug, "debug")
+	require.NoError(t, h.SetPackageLevel("nested", slog.LevelDebug))
+	assert.Equal(t, ambiguousWarning, out.String())
+}
+
+// TestWarnAmbiguousShortNamesRuntime tests warning about a filter naming packages with different import paths
+// once it's set at runtime.
+func TestWarnAmbiguousShortNamesRuntime(t *testing.T) {
+	t.Setenv("GO_LOG", "info,db=debug")
+
+	h := NewHandler(discardHandler{}, WithWarnAmbiguousShortNames(true))
+	var out bytes.Buffer
+	h.ambiguous.out = &out
+
+	logger := slog.New(h)
+	testpackage.LogSomething(logger, slog.LevelDebug, "debug")
+	shadow.LogSomething(logger, slog.LevelDebug, "debug")
+	require.NoError(t, h.SetPackageLevel("github.com/cbrewster/slog-env/internal/testpackage", slog.LevelDebug))
+	assert.Empty(t, out.String())
+
+	require.NoError(t, h.SetPackageLevel("testpackage", slog.LevelDebug))
+	assert.Equal(t, ambiguousWarning, out.String())
+}
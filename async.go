@@ -0,0 +1,116 @@
+package slogenv
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// WithAsync passes records to the inner handler from a background goroutine, so logging doesn't wait
+// for slow inner handlers. Up to bufferSize records are queued. When the queue is full, Handle blocks
+// until there is room rather than dropping records. Errors from the inner handler can't be returned
+// from Handle in this mode and are discarded, unless WithErrorHandler is set.
+//
+// Call Flush to wait for queued records to be handled, and Close to drain the queue and stop the goroutine.
+// After Close, records are handled synchronously.
+func WithAsync(bufferSize int) Opt {
+	return func(cfg *config) {
+		cfg.asyncBufferSize = bufferSize
+	}
+}
+
+// asyncItem is a record queued for a handler.
+type asyncItem struct {
+	ctx     context.Context
+	handler *Handler
+	record  slog.Record
+}
+
+// asyncQueue passes records to their inner handlers from a background goroutine.
+type asyncQueue struct {
+	// closeMu guards closed and sending on items, so items is never sent on after it is closed.
+	closeMu sync.RWMutex
+	closed  bool
+	items   chan asyncItem
+	done    chan struct{}
+
+	// pendingMu guards pending, the number of queued records not yet handled.
+	pendingMu sync.Mutex
+	pending   int
+	drained   *sync.Cond
+}
+
+// newAsyncQueue creates a queue and starts its goroutine.
+func newAsyncQueue(bufferSize int) *asyncQueue {
+	q := &asyncQueue{
+		items: make(chan asyncItem, bufferSize),
+		done:  make(chan struct{}),
+	}
+	q.drained = sync.NewCond(&q.pendingMu)
+
+	go q.run()
+	return q
+}
+
+// run handles queued records until the queue is closed.
+func (q *asyncQueue) run() {
+	defer close(q.done)
+
+	for item := range q.items {
+		_ = item.handler.handleInner(item.ctx, item.record)
+
+		q.pendingMu.Lock()
+		q.pending--
+		if q.pending == 0 {
+			q.drained.Broadcast()
+		}
+		q.pendingMu.Unlock()
+	}
+}
+
+// enqueue queues the record for the handler. It returns false if the queue is closed.
+func (q *asyncQueue) enqueue(ctx context.Context, h *Handler, record slog.Record) bool {
+	q.closeMu.RLock()
+	defer q.closeMu.RUnlock()
+
+	if q.closed {
+		return false
+	}
+
+	q.pendingMu.Lock()
+	q.pending++
+	q.pendingMu.Unlock()
+
+	q.items <- asyncItem{ctx: ctx, handler: h, record: record.Clone()}
+	return true
+}
+
+// flush waits until every queued record has been handled.
+func (q *asyncQueue) flush() {
+	q.pendingMu.Lock()
+	defer q.pendingMu.Unlock()
+
+	for q.pending > 0 {
+		q.drained.Wait()
+	}
+}
+
+// close stops accepting records and waits for the queued records to be handled.
+func (q *asyncQueue) close() {
+	q.closeMu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.items)
+	}
+	q.closeMu.Unlock()
+
+	<-q.done
+}
+
+// Flush waits until records queued by WithAsync have been passed to the inner handler.
+// It returns immediately if async handling isn't enabled.
+func (h *Handler) Flush() {
+	if h.async != nil {
+		h.async.flush()
+	}
+}
@@ -0,0 +1,115 @@
+package slogenv_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+)
+
+// blockingHandler records messages, but only handles records once released.
+type blockingHandler struct {
+	release  chan struct{}
+	mu       sync.Mutex
+	messages []string
+}
+
+// Enabled implements slog.Handler.
+func (*blockingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+// Handle implements slog.Handler.
+func (h *blockingHandler) Handle(_ context.Context, record slog.Record) error {
+	<-h.release
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.messages = append(h.messages, record.Message)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *blockingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+// WithGroup implements slog.Handler.
+func (h *blockingHandler) WithGroup(string) slog.Handler { return h }
+
+// handled returns the messages handled so far.
+func (h *blockingHandler) handled() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.messages...)
+}
+
+// TestAsync tests that records are handled in the background and delivered by Flush.
+func TestAsync(t *testing.T) {
+	os.Setenv("GO_LOG", "info,testpackage=debug")
+	defer os.Unsetenv("GO_LOG")
+
+	h := &blockingHandler{release: make(chan struct{})}
+	handler := slogenv.NewHandler(h, slogenv.WithAsync(16))
+	logger := slog.New(handler)
+
+	// Logging returns even though the inner handler is blocked.
+	logger.Debug("debug")
+	logger.Info("info")
+	testpackage.LogSomething(logger.With("key", "value"), slog.LevelDebug, "testpackage debug")
+	assert.Empty(t, h.handled())
+
+	close(h.release)
+	handler.Flush()
+	assert.Equal(t, []string{"info", "testpackage debug"}, h.handled())
+
+	require.NoError(t, handler.Close())
+}
+
+// TestAsyncCloseDrains tests that Close handles every queued record, and that logging after Close is synchronous.
+func TestAsyncCloseDrains(t *testing.T) {
+	os.Unsetenv("GO_LOG")
+
+	h := &blockingHandler{release: make(chan struct{})}
+	handler := slogenv.NewHandler(h, slogenv.WithAsync(100))
+	logger := slog.New(handler)
+
+	for i := 0; i < 50; i++ {
+		logger.Info("queued")
+	}
+
+	close(h.release)
+	require.NoError(t, handler.Close())
+	assert.Len(t, h.handled(), 50)
+
+	logger.Info("after close")
+	assert.Len(t, h.handled(), 51)
+}
+
+// TestAsyncErrorHandler tests that errors from records handled in the background reach the error handler.
+func TestAsyncErrorHandler(t *testing.T) {
+	os.Unsetenv("GO_LOG")
+
+	errDiskFull := errors.New("disk full")
+	var mu sync.Mutex
+	var errs []error
+	handler := slogenv.NewHandler(errorHandler{err: errDiskFull}, slogenv.WithAsync(16), slogenv.WithErrorHandler(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}))
+
+	logger := slog.New(handler)
+	logger.Info("first")
+	logger.Info("second")
+	handler.Flush()
+
+	mu.Lock()
+	assert.Equal(t, []error{errDiskFull, errDiskFull}, errs)
+	mu.Unlock()
+
+	require.NoError(t, handler.Close())
+}
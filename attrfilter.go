@@ -0,0 +1,84 @@
+package slogenv
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// attrPrefix marks filter keys which match the value of a record's attribute rather than its package.
+const attrPrefix = "attr:"
+
+// WithAttrFilter enables filters on the value of the attribute key, written as attr:key=value=level,
+// for records whose subsystem is identified by an attribute rather than a Go package.
+// With WithAttrFilter("component"), GO_LOG=info,attr:component=billing=debug sets the level to debug for
+// records with a component attribute of billing, whether the attribute is on the record itself or was added
+// with WithAttrs. Attributes on the record take precedence over those added with WithAttrs, and later ones
+// over earlier ones. Attributes are matched by key regardless of any groups they are in, and values are
+// compared as formatted by slog.Value.String.
+//
+// Attribute filters take precedence over every other filter except group filters. Only keys enabled with
+// WithAttrFilter are checked, since attributes added with WithAttrs have to be tracked as handlers are derived.
+// Call it once for each key.
+func WithAttrFilter(key string) Opt {
+	return func(cfg *config) {
+		cfg.attrFilterKeys = append(cfg.attrFilterKeys, key)
+	}
+}
+
+// isAttrKey reports whether a filter key is for an attribute value rather than a package.
+func isAttrKey(key string) bool {
+	return strings.HasPrefix(key, attrPrefix)
+}
+
+// isAttrFilterKey reports whether attributes with key are checked against attribute filters.
+func (h *Handler) isAttrFilterKey(key string) bool {
+	for _, k := range h.cfg.attrFilterKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// trackAttrs returns the attributes with keys checked by attribute filters from attrs.
+func (h *Handler) trackAttrs(attrs []slog.Attr) []slog.Attr {
+	var tracked []slog.Attr
+	for _, attr := range attrs {
+		if h.isAttrFilterKey(attr.Key) {
+			tracked = append(tracked, attr)
+		}
+	}
+	return tracked
+}
+
+// matchAttrFilter returns the key and level of the attribute filter in state matching the record's attributes,
+// or the attributes this handler was derived with.
+func (h *Handler) matchAttrFilter(state *levelState, record slog.Record) (string, slog.Level, bool) {
+	var rule string
+	var level slog.Level
+	var found bool
+	record.Attrs(func(attr slog.Attr) bool {
+		if h.isAttrFilterKey(attr.Key) {
+			if l, ok := state.perPackageLevel[attrFilterKey(attr)]; ok {
+				rule, level, found = attrFilterKey(attr), l, true
+			}
+		}
+		return true
+	})
+	if found {
+		return rule, level, true
+	}
+
+	for i := len(h.attrs) - 1; i >= 0; i-- {
+		key := attrFilterKey(h.attrs[i])
+		if l, ok := state.perPackageLevel[key]; ok {
+			return key, l, true
+		}
+	}
+	return "", 0, false
+}
+
+// attrFilterKey returns the filter key matching attr.
+func attrFilterKey(attr slog.Attr) string {
+	return attrPrefix + attr.Key + "=" + attr.Value.Resolve().String()
+}
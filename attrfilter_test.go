@@ -0,0 +1,77 @@
+package slogenv_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestAttrFilter tests filtering by the value of an attribute on the record or added with WithAttrs.
+func TestAttrFilter(t *testing.T) {
+	t.Setenv("GO_LOG", "info,attr:component=billing=debug,attr:component=search=error,testpackage=warn")
+
+	h := slogenvtest.NewCaptureHandler()
+	logger := slog.New(slogenv.NewHandler(h, slogenv.WithAttrFilter("component")))
+
+	// Attributes on the record.
+	logger.Debug("billing debug", "component", "billing")
+	logger.Debug("other debug", "component", "other")
+	logger.Warn("search warn", "component", "search")
+	testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+
+	// Attributes added with WithAttrs, including through groups and further derivations.
+	billing := logger.With("component", "billing")
+	billing.Debug("derived billing debug")
+	billing.WithGroup("request").With("id", 1).Debug("nested billing debug")
+	testpackage.LogSomething(billing, slog.LevelDebug, "testpackage billing debug")
+
+	// Attributes on the record take precedence over those added with WithAttrs, and later ones over earlier ones.
+	billing.Warn("record search warn", "component", "search")
+	logger.With("component", "search").With("component", "billing").Debug("later billing debug")
+
+	// Other keys aren't checked.
+	logger.Debug("team debug", "team", "billing")
+
+	assert.Equal(t, []string{
+		"billing debug",
+		"derived billing debug",
+		"nested billing debug",
+		"testpackage billing debug",
+		"later billing debug",
+	}, h.Messages())
+}
+
+// TestAttrFilterKeys tests that attribute filters only apply to keys enabled with WithAttrFilter.
+func TestAttrFilterKeys(t *testing.T) {
+	t.Setenv("GO_LOG", "info,attr:component=billing=debug,attr:tenant=acme=debug")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h, slogenv.WithAttrFilter("tenant"))
+	logger := slog.New(handler)
+
+	logger.Debug("billing debug", "component", "billing")
+	logger.Debug("acme debug", "tenant", "acme")
+	logger.With("tenant", "acme").Debug("derived acme debug")
+	assert.Equal(t, []string{"acme debug", "derived acme debug"}, h.Messages())
+
+	assert.Equal(t, map[string]slog.Level{
+		"attr:component=billing": slog.LevelDebug,
+		"attr:tenant=acme":       slog.LevelDebug,
+	}, handler.PackageLevels())
+
+	record := testpackage.Record(slog.LevelDebug, "trace")
+	record.AddAttrs(slog.String("tenant", "acme"))
+	assert.Equal(t, slogenv.ResolutionTrace{
+		Package:    "testpackage",
+		Considered: []string{"attr:tenant=acme"},
+		Rule:       "attr:tenant=acme=DEBUG",
+		Threshold:  slog.LevelDebug,
+		Emitted:    true,
+	}, handler.Trace(context.Background(), record))
+}
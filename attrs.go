@@ -0,0 +1,87 @@
+package slogenv
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// AttrLimitPolicy decides what happens to records with more attributes than allowed by WithMaxAttrsForPackage.
+type AttrLimitPolicy int
+
+const (
+	// AttrLimitDrop drops records with too many attributes.
+	AttrLimitDrop AttrLimitPolicy = iota
+	// AttrLimitTruncate keeps only the first attributes of records with too many attributes.
+	AttrLimitTruncate
+)
+
+// WithMaxAttrsForPackage limits records from pkg to n attributes while pkg is configured at debug or more verbose,
+// since verbose packages sometimes attach large sets of attributes. Records exceeding the limit are handled
+// according to WithMaxAttrsPolicy. Only attributes on the record itself are counted, not those added via WithAttrs.
+func WithMaxAttrsForPackage(pkg string, n int) Opt {
+	return func(cfg *config) {
+		if cfg.maxAttrs == nil {
+			cfg.maxAttrs = make(map[string]int)
+		}
+		cfg.maxAttrs[pkg] = n
+	}
+}
+
+// WithMaxAttrsPolicy sets what happens to records exceeding WithMaxAttrsForPackage. Default is AttrLimitDrop.
+func WithMaxAttrsPolicy(policy AttrLimitPolicy) Opt {
+	return func(cfg *config) {
+		cfg.maxAttrsPolicy = policy
+	}
+}
+
+// limitAttrs applies the attribute limit for the record's package. It returns false if the record should be dropped.
+func (h *Handler) limitAttrs(record slog.Record, res resolution) (slog.Record, bool) {
+	pkg, path := h.recordPackage(record, res)
+	n, ok := h.maxAttrs[pkg]
+	if !ok || record.NumAttrs() <= n {
+		return record, true
+	}
+
+	if level, _, ok := h.packageLevel(h.levels(), pkg, path); !ok || level > slog.LevelDebug {
+		return record, true
+	}
+
+	if h.maxAttrsPolicy == AttrLimitDrop {
+		return record, false
+	}
+
+	return truncateAttrs(record, n), true
+}
+
+// attrPool holds attribute slices used while rewriting records, so rewriting doesn't allocate a slice per record.
+// Only features which rewrite records touch the pool.
+var attrPool = sync.Pool{
+	New: func() any {
+		attrs := make([]slog.Attr, 0, 16)
+		return &attrs
+	},
+}
+
+// truncateAttrs returns a copy of record with only its first n attributes.
+func truncateAttrs(record slog.Record, n int) slog.Record {
+	attrsPtr := attrPool.Get().(*[]slog.Attr)
+	attrs := (*attrsPtr)[:0]
+
+	record.Attrs(func(attr slog.Attr) bool {
+		if len(attrs) >= n {
+			return false
+		}
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	// AddAttrs copies the attributes into the record, so the slice can be reused afterwards.
+	truncated := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	truncated.AddAttrs(attrs...)
+
+	clear(attrs)
+	*attrsPtr = attrs[:0]
+	attrPool.Put(attrsPtr)
+
+	return truncated
+}
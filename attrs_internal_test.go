@@ -0,0 +1,72 @@
+package slogenv
+
+import (
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newAttrsRecord creates a record with n integer attributes named after their index.
+func newAttrsRecord(n int, offset int) slog.Record {
+	record := slog.NewRecord(time.Now(), slog.LevelDebug, "message", 0)
+	for i := 0; i < n; i++ {
+		record.AddAttrs(slog.Int(strconv.Itoa(i), i+offset))
+	}
+	return record
+}
+
+// recordValues returns the integer values of a record's attributes.
+func recordValues(record slog.Record) []int64 {
+	var values []int64
+	record.Attrs(func(attr slog.Attr) bool {
+		values = append(values, attr.Value.Int64())
+		return true
+	})
+	return values
+}
+
+// TestTruncateAttrsNoAliasing tests that records truncated with pooled slices don't share attributes.
+func TestTruncateAttrsNoAliasing(t *testing.T) {
+	first := truncateAttrs(newAttrsRecord(12, 0), 8)
+	second := truncateAttrs(newAttrsRecord(12, 100), 8)
+	third := truncateAttrs(newAttrsRecord(3, 200), 8)
+
+	assert.Equal(t, []int64{0, 1, 2, 3, 4, 5, 6, 7}, recordValues(first))
+	assert.Equal(t, []int64{100, 101, 102, 103, 104, 105, 106, 107}, recordValues(second))
+	assert.Equal(t, []int64{200, 201, 202}, recordValues(third))
+}
+
+// truncateAttrsUnpooled is the straightforward implementation of truncateAttrs, used as a benchmark baseline.
+func truncateAttrsUnpooled(record slog.Record, n int) slog.Record {
+	truncated := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		if truncated.NumAttrs() >= n {
+			return false
+		}
+		truncated.AddAttrs(attr)
+		return true
+	})
+	return truncated
+}
+
+// BenchmarkTruncateAttrs compares allocations when truncating records with and without the attr pool.
+func BenchmarkTruncateAttrs(b *testing.B) {
+	record := newAttrsRecord(16, 0)
+
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			truncateAttrsUnpooled(record, 12)
+		}
+	})
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			truncateAttrs(record, 12)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package slogenv_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestMaxAttrsForPackage tests the drop and truncate policies for records with too many attributes.
+func TestMaxAttrsForPackage(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		filter       string
+		policy       slogenv.AttrLimitPolicy
+		wantMessages []string
+		wantAttrs    []int
+	}{
+		{
+			name:         "drop",
+			filter:       "info,testpackage=debug",
+			policy:       slogenv.AttrLimitDrop,
+			wantMessages: []string{"small", "large from test"},
+			wantAttrs:    []int{2, 4},
+		},
+		{
+			name:         "truncate",
+			filter:       "info,testpackage=debug",
+			policy:       slogenv.AttrLimitTruncate,
+			wantMessages: []string{"small", "large", "large from test"},
+			wantAttrs:    []int{2, 2, 4},
+		},
+		{
+			name:         "not at debug",
+			filter:       "info,testpackage=info",
+			policy:       slogenv.AttrLimitDrop,
+			wantMessages: []string{"small", "large", "large from test"},
+			wantAttrs:    []int{2, 4, 4},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			os.Setenv("GO_LOG", test.filter)
+			defer os.Unsetenv("GO_LOG")
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h,
+				slogenv.WithMaxAttrsForPackage("testpackage", 2),
+				slogenv.WithMaxAttrsPolicy(test.policy),
+			))
+			testpackage.LogAttrs(logger, slog.LevelInfo, "small", slog.Int("a", 1), slog.Int("b", 2))
+			testpackage.LogAttrs(logger, slog.LevelInfo, "large", slog.Int("a", 1), slog.Int("b", 2), slog.Int("c", 3), slog.Int("d", 4))
+			logger.LogAttrs(context.Background(), slog.LevelInfo, "large from test", slog.Int("a", 1), slog.Int("b", 2), slog.Int("c", 3), slog.Int("d", 4))
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+			var attrs []int
+			for _, record := range h.Records() {
+				attrs = append(attrs, record.NumAttrs())
+			}
+			assert.Equal(t, test.wantAttrs, attrs)
+		})
+	}
+}
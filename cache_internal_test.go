@@ -0,0 +1,227 @@
+package slogenv
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/internal/testpackage/nested"
+)
+
+// discardHandler drops every record.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return true }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// cachedPackages returns the number of program counters in the handler's package cache.
+func cachedPackages(h *Handler) int {
+	n := 0
+	h.packages.Range(func(any, any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// TestPackageCacheBounded tests that the package cache holds one entry per call site,
+// no matter how many records are logged from it.
+func TestPackageCacheBounded(t *testing.T) {
+	t.Setenv("GO_LOG", "info,testpackage=debug")
+
+	h := NewHandler(discardHandler{})
+	logger := slog.New(h)
+	for i := 0; i < 1000; i++ {
+		testpackage.LogSomething(logger, slog.LevelDebug, "debug")
+		testpackage.LogContext(context.Background(), logger.With("i", i), slog.LevelDebug, "debug")
+	}
+
+	assert.Equal(t, 2, cachedPackages(h))
+	assert.Equal(t, slog.LevelDebug, h.LevelForPC(testpackage.CallerPC()))
+}
+
+// TestPackageCacheSkipStdlibFrames tests that records attributed to a caller outside the standard library
+// aren't cached, since the caller depends on the stack rather than the program counter.
+func TestPackageCacheSkipStdlibFrames(t *testing.T) {
+	t.Setenv("GO_LOG", "info")
+
+	h := NewHandler(discardHandler{}, WithSkipStdlibFrames(true))
+	pc := reflect.ValueOf(strings.ToUpper).Pointer()
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "message", pc)
+	_ = h.Handle(context.Background(), record)
+
+	assert.Equal(t, 0, cachedPackages(h))
+}
+
+// TestPackageCacheNoPC tests that records without a program counter use the default level
+// without being resolved or cached.
+func TestPackageCacheNoPC(t *testing.T) {
+	t.Setenv("GO_LOG", "warn,slogenv=debug,testpackage=debug,*/*=debug")
+
+	h := NewHandler(discardHandler{})
+	res := h.getLevelForRecord(context.Background(), slog.NewRecord(time.Now(), slog.LevelDebug, "message", 0))
+	assert.Equal(t, resolution{level: slog.LevelWarn}, res)
+	assert.Equal(t, 0, cachedPackages(h))
+	assert.Equal(t, resolvedPackage{}, h.resolveCaller(0))
+}
+
+// BenchmarkCallerFunction compares resolving the function of a program counter with FuncForPC and CallersFrames.
+func BenchmarkCallerFunction(b *testing.B) {
+	pc := testpackage.CallerPC()
+
+	b.Run("FuncForPC", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			callerFrame(pc)
+		}
+	})
+	b.Run("CallersFrames", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			runtime.CallersFrames([]uintptr{pc}).Next()
+		}
+	})
+}
+
+// BenchmarkResolvePackage compares resolving packages with and without the package cache.
+func BenchmarkResolvePackage(b *testing.B) {
+	b.Setenv("GO_LOG", "warn,testpackage=info")
+
+	for _, bench := range []struct {
+		name   string
+		cached bool
+	}{
+		{name: "cached", cached: true},
+		{name: "uncached", cached: false},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			h := NewHandler(discardHandler{})
+			if !bench.cached {
+				h.packages = nil
+			}
+			logger := slog.New(h)
+
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					testpackage.LogSomething(logger, slog.LevelInfo, "message")
+				}
+			})
+		})
+	}
+}
+
+// distinctPCs returns program counters in n distinct functions, for benchmarking packages resolved
+// for the first time.
+func distinctPCs(n int) []uintptr {
+	funcs := []any{
+		strings.ToUpper, strings.ToLower, strings.TrimSpace, strings.Fields, strings.Title, strings.Repeat,
+		time.Now, time.Since, time.Sleep, time.Unix, reflect.TypeOf, reflect.ValueOf,
+		runtime.Gosched, runtime.NumCPU, runtime.GC, runtime.Callers,
+		testpackage.LogSomething, testpackage.LogContext, testpackage.LogAttrs, testpackage.CallerPC,
+	}
+	pcs := make([]uintptr, 0, n)
+	for i := 0; i < n; i++ {
+		// callerFrame looks up the instruction before the return address, so step past the entry.
+		pcs = append(pcs, reflect.ValueOf(funcs[i%len(funcs)]).Pointer()+1)
+	}
+	return pcs
+}
+
+// BenchmarkResolveDistinctPCs benchmarks resolving the package of many distinct call sites without the
+// package cache, as happens the first time each call site logs. Splitting the function name to parse
+// the package allocated once per call site, 20 allocs/op for 20 call sites, which it no longer does.
+func BenchmarkResolveDistinctPCs(b *testing.B) {
+	b.Setenv("GO_LOG", "warn,testpackage=info")
+
+	for _, n := range []int{1, 20} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			h := NewHandler(discardHandler{})
+			h.packages = nil
+			pcs := distinctPCs(n)
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, pc := range pcs {
+					h.resolveCaller(pc)
+				}
+			}
+		})
+	}
+}
+
+// callerSkippingStdlibUnpooled is callerSkippingStdlib without the stack pool, used as a benchmark baseline.
+func callerSkippingStdlibUnpooled(pc uintptr) (runtime.Frame, bool) {
+	pcs := make([]uintptr, maxStackDepth)
+	pcs = pcs[:runtime.Callers(1, pcs)]
+	for i, callerPC := range pcs {
+		if callerPC == pc {
+			return firstNonStdlibFrame(runtime.CallersFrames(pcs[i:]).Next)
+		}
+	}
+	return runtime.Frame{}, false
+}
+
+// BenchmarkCallerSkippingStdlib compares allocations when walking the stack with and without the stack pool.
+// Without it, every record logged from the standard library allocates a 512 byte buffer.
+func BenchmarkCallerSkippingStdlib(b *testing.B) {
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			callerSkippingStdlibUnpooled(pcs[0])
+		}
+	})
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			callerSkippingStdlib(pcs[0])
+		}
+	})
+}
+
+// TestPackageCacheSurvivesReload tests that resolved packages stay cached when the levels change at runtime,
+// while records still get the new levels.
+func TestPackageCacheSurvivesReload(t *testing.T) {
+	t.Setenv("GO_LOG", "info,testpackage=debug,nested=debug")
+
+	h := NewHandler(discardHandler{})
+	logger := slog.New(h)
+	testpackage.LogSomething(logger, slog.LevelDebug, "debug")
+	nested.LogSomething(logger, slog.LevelDebug, "debug")
+	pc := testpackage.CallerPC()
+	assert.Equal(t, slog.LevelDebug, h.LevelForPC(pc))
+
+	cached := make(map[any]any)
+	h.packages.Range(func(pc, resolved any) bool {
+		cached[pc] = resolved
+		return true
+	})
+	require.Len(t, cached, 3)
+
+	require.NoError(t, h.SetPackageLevel("testpackage", slog.LevelWarn))
+	require.NoError(t, h.reloadFilter("info,testpackage=error,nested=debug"))
+
+	assert.Equal(t, slog.LevelError, h.LevelForPC(pc))
+	for pc, resolved := range cached {
+		entry, ok := h.packages.Load(pc)
+		if assert.True(t, ok) {
+			assert.Equal(t, resolved, entry)
+		}
+	}
+	assert.Equal(t, len(cached), cachedPackages(h))
+}
@@ -0,0 +1,76 @@
+package slogenv
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxCompactFilterSize is the largest filter a compact filter is decoded into, so a corrupt value
+// can't decompress into an unbounded amount of memory.
+const maxCompactFilterSize = 1 << 20
+
+// WithCompactFilterEnvVar reads the filter from the environment variable name in the compact form created by
+// EncodeCompactFilter, for platforms which limit the length of environment variables or the characters in them.
+// It is only used if none of the environment variables set by WithEnvVarName or WithEnvVarNames are set,
+// and otherwise is treated the same way as them. If the value can't be decoded, NewHandlerWithError reports
+// the problem and the filter is loaded as if the variable wasn't set.
+func WithCompactFilterEnvVar(name string) Opt {
+	return func(cfg *config) {
+		cfg.compactEnvVar = name
+	}
+}
+
+// EncodeCompactFilter encodes filter in a compact form for WithCompactFilterEnvVar. The filter is compressed,
+// which shrinks filters with many packages sharing import path prefixes, and encoded using the URL-safe
+// base64 alphabet without padding, so it only contains letters, digits, - and _.
+func EncodeCompactFilter(filter string) string {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestCompression)
+	_, _ = w.Write([]byte(filter))
+	_ = w.Close()
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes())
+}
+
+// DecodeCompactFilter decodes a filter encoded by EncodeCompactFilter.
+// Padding, and the standard base64 alphabet, are also accepted.
+func DecodeCompactFilter(compact string) (string, error) {
+	compact = strings.TrimRight(strings.TrimSpace(compact), "=")
+	compact = strings.NewReplacer("+", "-", "/", "_").Replace(compact)
+	data, err := base64.RawURLEncoding.DecodeString(compact)
+	if err != nil {
+		return "", fmt.Errorf("invalid compact filter: %w", err)
+	}
+
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	filter, err := io.ReadAll(io.LimitReader(r, maxCompactFilterSize+1))
+	if err != nil {
+		return "", fmt.Errorf("invalid compact filter: %w", err)
+	}
+	if len(filter) > maxCompactFilterSize {
+		return "", fmt.Errorf("invalid compact filter: longer than %d bytes", maxCompactFilterSize)
+	}
+	return string(filter), nil
+}
+
+// compactEnvFilter returns the filter decoded from the compact environment variable, if one is configured and set.
+func (cfg *config) compactEnvFilter() (string, error) {
+	if cfg.compactEnvVar == "" {
+		return "", nil
+	}
+	compact := os.Getenv(cfg.compactEnvVar)
+	if compact == "" {
+		return "", nil
+	}
+
+	filter, err := DecodeCompactFilter(compact)
+	if err != nil {
+		return "", fmt.Errorf("decoding %s: %w", cfg.compactEnvVar, err)
+	}
+	return filter, nil
+}
@@ -0,0 +1,106 @@
+package slogenv_test
+
+import (
+	"encoding/base64"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestCompactFilterRoundTrip tests that encoded filters decode back to the same filter.
+func TestCompactFilterRoundTrip(t *testing.T) {
+	filter := "warn,github.com/acme/api=debug,github.com/acme/db=error,github.com/acme/cache=info," +
+		"github.com/acme/auth=debug,github.com/acme/billing=warn,group:http/*=error"
+
+	compact := slogenv.EncodeCompactFilter(filter)
+	assert.Less(t, len(compact), len(filter))
+	assert.Regexp(t, `^[A-Za-z0-9_-]+$`, compact)
+
+	decoded, err := slogenv.DecodeCompactFilter(compact)
+	require.NoError(t, err)
+	assert.Equal(t, filter, decoded)
+
+	// Padding and the standard alphabet are accepted too.
+	raw, err := base64.RawURLEncoding.DecodeString(compact)
+	require.NoError(t, err)
+	decoded, err = slogenv.DecodeCompactFilter(base64.StdEncoding.EncodeToString(raw))
+	require.NoError(t, err)
+	assert.Equal(t, filter, decoded)
+
+	decoded, err = slogenv.DecodeCompactFilter(slogenv.EncodeCompactFilter(""))
+	require.NoError(t, err)
+	assert.Empty(t, decoded)
+
+	_, err = slogenv.DecodeCompactFilter("not a filter!")
+	assert.Error(t, err)
+	_, err = slogenv.DecodeCompactFilter(base64.RawURLEncoding.EncodeToString([]byte("plain")))
+	assert.Error(t, err)
+}
+
+// TestCompactFilterEnvVar tests that filters read in compact form are applied like the same filter in GO_LOG.
+func TestCompactFilterEnvVar(t *testing.T) {
+	filter := "warn,testpackage=debug,db=error,cache=info"
+	compact := slogenv.EncodeCompactFilter(filter)
+
+	for _, test := range []struct {
+		name         string
+		env          string
+		compact      string
+		wantMessages []string
+		wantErr      string
+	}{
+		{
+			name:         "compact",
+			compact:      compact,
+			wantMessages: []string{"warn", "testpackage debug"},
+		},
+		{
+			name:         "plain takes precedence",
+			env:          "info",
+			compact:      compact,
+			wantMessages: []string{"info", "warn"},
+		},
+		{
+			name:         "invalid",
+			compact:      "!!!",
+			wantMessages: []string{"info", "warn"},
+			wantErr:      "decoding GO_LOG_COMPACT: invalid compact filter",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("GO_LOG", test.env)
+			t.Setenv("GO_LOG_COMPACT", test.compact)
+
+			h := slogenvtest.NewCaptureHandler()
+			handler, err := slogenv.NewHandlerWithError(h, slogenv.WithCompactFilterEnvVar("GO_LOG_COMPACT"))
+			if test.wantErr != "" {
+				require.Error(t, err)
+				assert.True(t, strings.HasPrefix(err.Error(), test.wantErr), err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+			logger := slog.New(handler)
+			logger.Info("info")
+			logger.Warn("warn")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+
+	t.Setenv("GO_LOG", filter)
+	t.Setenv("GO_LOG_COMPACT", "")
+	plain := slogenv.NewHandler(slogenvtest.NewCaptureHandler())
+	t.Setenv("GO_LOG", "")
+	t.Setenv("GO_LOG_COMPACT", compact)
+	decoded := slogenv.NewHandler(slogenvtest.NewCaptureHandler(), slogenv.WithCompactFilterEnvVar("GO_LOG_COMPACT"))
+	assert.Equal(t, plain.EffectiveLevels(), decoded.EffectiveLevels())
+	assert.Equal(t, plain.DefaultLevel(), decoded.DefaultLevel())
+}
@@ -0,0 +1,71 @@
+package slogenv
+
+import "log/slog"
+
+// Config builds a handler from settings added one at a time, for code which decides how to configure
+// the handler as it goes, such as from a configuration file. Each setter is equivalent to the option
+// of the same name, and returns the Config so calls can be chained:
+//
+//	handler := slogenv.NewConfig().
+//		DefaultLevel(slog.LevelWarn).
+//		EnvVarName("APP_LOG").
+//		With(slogenv.WithTestConvenience(true)).
+//		Build(inner)
+//
+// Settings are applied in the order they were added, like options passed to NewHandler.
+// The zero Config is ready to use.
+type Config struct {
+	opts []Opt
+}
+
+// NewConfig creates an empty Config.
+func NewConfig() *Config {
+	return &Config{}
+}
+
+// DefaultLevel sets the level for packages without a filter, see WithDefaultLevel.
+func (c *Config) DefaultLevel(level slog.Level) *Config {
+	return c.With(WithDefaultLevel(level))
+}
+
+// EnvVarName sets the environment variable the filter is read from, see WithEnvVarName.
+func (c *Config) EnvVarName(name string) *Config {
+	return c.With(WithEnvVarName(name))
+}
+
+// EnvVarNames sets several environment variables the filter is read from, see WithEnvVarNames.
+func (c *Config) EnvVarNames(names ...string) *Config {
+	return c.With(WithEnvVarNames(names...))
+}
+
+// DefaultFilter sets the filter used if the environment variable is not set, see WithDefaultFilter.
+func (c *Config) DefaultFilter(filter string) *Config {
+	return c.With(WithDefaultFilter(filter))
+}
+
+// PackageLevels sets the level for each package in code, see WithPackageLevels.
+func (c *Config) PackageLevels(levels map[string]slog.Level) *Config {
+	return c.With(WithPackageLevels(levels))
+}
+
+// With adds options which don't have a setter of their own.
+func (c *Config) With(opts ...Opt) *Config {
+	c.opts = append(c.opts, opts...)
+	return c
+}
+
+// Opts returns a copy of the options added so far, which can be passed to NewHandler.
+func (c *Config) Opts() []Opt {
+	return append([]Opt(nil), c.opts...)
+}
+
+// Build creates a handler wrapping inner with the settings added so far, like NewHandler.
+func (c *Config) Build(inner slog.Handler) *Handler {
+	return NewHandler(inner, c.opts...)
+}
+
+// BuildWithError creates a handler wrapping inner with the settings added so far, reporting any problems
+// with its configuration, like NewHandlerWithError.
+func (c *Config) BuildWithError(inner slog.Handler) (*Handler, error) {
+	return NewHandlerWithError(inner, c.opts...)
+}
@@ -0,0 +1,66 @@
+package slogenv_test
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestConfig tests that a handler built from a Config behaves like one built from the same options.
+func TestConfig(t *testing.T) {
+	t.Setenv("APP_LOG", "")
+	t.Setenv("OLD_LOG", "testpackage=debug")
+
+	logAll := func(handler *slogenv.Handler) {
+		logger := slog.New(handler)
+		logger.Debug("debug")
+		logger.Info("info")
+		logger.Warn("warn")
+		testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+		testpackage.LogSomething(logger, slog.LevelInfo, "testpackage info")
+	}
+
+	config := slogenv.NewConfig().
+		DefaultLevel(slog.LevelWarn).
+		EnvVarNames("APP_LOG", "OLD_LOG").
+		DefaultFilter("error").
+		With(slogenv.WithTestConvenience(true))
+
+	built := slogenvtest.NewCaptureHandler()
+	handler := config.Build(built)
+	logAll(handler)
+
+	fromOpts := slogenvtest.NewCaptureHandler()
+	logAll(slogenv.NewHandler(fromOpts,
+		slogenv.WithDefaultLevel(slog.LevelWarn),
+		slogenv.WithEnvVarNames("APP_LOG", "OLD_LOG"),
+		slogenv.WithDefaultFilter("error"),
+		slogenv.WithTestConvenience(true),
+	))
+
+	assert.Equal(t, []string{"warn", "testpackage debug", "testpackage info"}, built.Messages())
+	assert.Equal(t, fromOpts.Messages(), built.Messages())
+	assert.Equal(t, slog.LevelWarn, handler.DefaultLevel())
+
+	// Later settings override earlier ones, like options.
+	t.Setenv("OLD_LOG", "")
+	handler = config.EnvVarName("APP_LOG").DefaultFilter("info").Build(slogenvtest.NewCaptureHandler())
+	assert.Equal(t, slog.LevelInfo, handler.DefaultLevel())
+	assert.Len(t, config.Opts(), 6)
+}
+
+// TestConfigBuildWithError tests that configuration problems are reported by BuildWithError.
+func TestConfigBuildWithError(t *testing.T) {
+	t.Setenv("GO_LOG", "")
+
+	var config slogenv.Config
+	handler, err := config.DefaultFilter("info,acme=loud").BuildWithError(slogenvtest.NewCaptureHandler())
+	require.NotNil(t, handler)
+	assert.EqualError(t, err, `invalid level "loud" for package "acme"`)
+}
@@ -0,0 +1,38 @@
+package slogenv
+
+import (
+	"context"
+	"log/slog"
+)
+
+// levelOverrideKey is the context key for level overrides.
+type levelOverrideKey struct{}
+
+// WithLevelOverride returns a context which overrides the level for records logged with it, regardless of
+// the package and default levels. For example, a request handler can log at debug for a single traced
+// request without changing the levels for everything else.
+func WithLevelOverride(ctx context.Context, level slog.Level) context.Context {
+	return context.WithValue(ctx, levelOverrideKey{}, level)
+}
+
+// WithBaseContext ties the background goroutines started for WithReloadOnSignal, WithFilterFile and
+// WithSuppressionSummary to ctx, so they stop when ctx is done, as well as when Close is called. Close still
+// sends the last suppression summary and stops handling records from WithAsync, whose goroutine isn't stopped
+// by ctx so queued records aren't lost.
+func WithBaseContext(ctx context.Context) Opt {
+	return func(cfg *config) {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		cfg.baseContext = ctx
+	}
+}
+
+// levelOverride returns the level override carried by ctx, if any.
+func levelOverride(ctx context.Context) (slog.Level, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	level, ok := ctx.Value(levelOverrideKey{}).(slog.Level)
+	return level, ok
+}
@@ -0,0 +1,56 @@
+package slogenv
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBaseContext tests that the background goroutines exit once the base context is canceled.
+func TestBaseContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter")
+	require.NoError(t, os.WriteFile(path, []byte("info"), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h := NewHandler(discardHandler{},
+		WithBaseContext(ctx),
+		WithReloadOnSignal(os.Interrupt),
+		WithFilterFile(path),
+		WithFilterFileInterval(time.Millisecond),
+		WithSuppressionSummary(time.Millisecond),
+	)
+
+	stopped := map[string]chan struct{}{
+		"reloader":    h.reloader.stopped,
+		"watcher":     h.watcher.stopped,
+		"suppression": h.suppression.stopped,
+	}
+	for name, ch := range stopped {
+		select {
+		case <-ch:
+			t.Fatalf("%s exited before the base context was canceled", name)
+		default:
+		}
+	}
+
+	cancel()
+	for name, ch := range stopped {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatalf("%s didn't exit after the base context was canceled", name)
+		}
+	}
+
+	// The filter file is no longer watched, and Close still works.
+	require.NoError(t, os.WriteFile(path, []byte("debug"), 0o600))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, slog.LevelInfo, h.DefaultLevel())
+	require.NoError(t, h.Close())
+}
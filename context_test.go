@@ -0,0 +1,53 @@
+package slogenv_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestLevelOverride tests that a level override in the context takes precedence over the filter.
+func TestLevelOverride(t *testing.T) {
+	t.Setenv("GO_LOG", "warn,testpackage=error")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h)
+	logger := slog.New(handler)
+
+	ctx := context.Background()
+	debugCtx := slogenv.WithLevelOverride(ctx, slog.LevelDebug)
+	errorCtx := slogenv.WithLevelOverride(ctx, slog.LevelError)
+
+	testpackage.LogContext(ctx, logger, slog.LevelDebug, "suppressed")
+	testpackage.LogContext(debugCtx, logger, slog.LevelDebug, "testpackage debug")
+	logger.DebugContext(debugCtx, "debug")
+	logger.WarnContext(errorCtx, "warn")
+	logger.WarnContext(ctx, "warn without override")
+
+	assert.Equal(t, []string{"testpackage debug", "debug", "warn without override"}, h.Messages())
+
+	assert.False(t, handler.Enabled(ctx, slog.LevelDebug))
+	assert.True(t, handler.Enabled(debugCtx, slog.LevelDebug))
+	assert.False(t, handler.Enabled(errorCtx, slog.LevelWarn))
+}
+
+// TestLevelOverrideTrace tests that tracing a record explains a level override in the context.
+func TestLevelOverrideTrace(t *testing.T) {
+	t.Setenv("GO_LOG", "warn")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler())
+	ctx := slogenv.WithLevelOverride(context.Background(), slog.LevelDebug)
+
+	assert.Equal(t, slogenv.ResolutionTrace{
+		Package:    "testpackage",
+		Considered: []string{"context"},
+		Threshold:  slog.LevelDebug,
+		Emitted:    true,
+	}, handler.Trace(ctx, testpackage.Record(slog.LevelInfo, "info")))
+}
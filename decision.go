@@ -0,0 +1,122 @@
+package slogenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultDecisionLogMaxBytes is the size at which the decision log is rotated.
+const defaultDecisionLogMaxBytes = 10 << 20
+
+// DecisionTrace describes how the handler decided whether to emit a single record.
+type DecisionTrace struct {
+	// Time is the time of the record.
+	Time time.Time `json:"time"`
+	// Message is the message of the record.
+	Message string `json:"message"`
+	// Level is the level of the record.
+	Level slog.Level `json:"level"`
+	// Package is the package the record was logged from, if it could be determined.
+	Package string `json:"package,omitempty"`
+	// Rule is the package filter which decided the threshold. It is empty if the default applied.
+	Rule string `json:"rule,omitempty"`
+	// Threshold is the minimum level the record needed to be emitted.
+	Threshold slog.Level `json:"threshold"`
+	// Emitted is true if the record was passed on to the inner handler.
+	Emitted bool `json:"emitted"`
+}
+
+// decisionLog writes decisions as JSON lines to a size-capped file.
+type decisionLog struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	written  int64
+	maxBytes int64
+	// rotateFailed is true once rotating the file failed, so the failure is only reported once.
+	rotateFailed bool
+}
+
+// openDecisionLog opens the decision log at path, appending to any existing file.
+func openDecisionLog(path string, maxBytes int64) (*decisionLog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &decisionLog{
+		path:     path,
+		file:     file,
+		written:  info.Size(),
+		maxBytes: maxBytes,
+	}, nil
+}
+
+// write appends the decision to the log, rotating the file if it grew too large.
+// Errors writing the file are ignored, so a broken decision log never stops records from being handled.
+func (l *decisionLog) write(decision DecisionTrace) {
+	line, err := json.Marshal(decision)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return
+	}
+
+	if l.written > 0 && l.written+int64(len(line)) > l.maxBytes {
+		l.rotate()
+	}
+
+	n, _ := l.file.Write(line)
+	l.written += int64(n)
+}
+
+// rotate moves the current file aside and starts a new one. If that fails, writing continues to the current
+// file and rotating is tried again on the next write, with the first failure reported on stderr.
+// Must be called with l.mu held.
+func (l *decisionLog) rotate() {
+	err := os.Rename(l.path, l.path+".1")
+	if err == nil {
+		var file *os.File
+		if file, err = os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644); err == nil {
+			l.file.Close()
+			l.file = file
+			l.written = 0
+			return
+		}
+	}
+
+	if !l.rotateFailed {
+		l.rotateFailed = true
+		fmt.Fprintf(os.Stderr, "slog-env: can't rotate decision log, writing to the current file: %v\n", err)
+	}
+}
+
+// close closes the underlying file. Further writes are dropped.
+func (l *decisionLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
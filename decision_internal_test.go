@@ -0,0 +1,58 @@
+package slogenv
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDecisionLogRotate tests that the decision log is moved aside once it grows past its maximum size,
+// keeping only the previous file.
+func TestDecisionLogRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	l, err := openDecisionLog(path, 100)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		l.write(DecisionTrace{Message: "decision"})
+	}
+	require.NoError(t, l.close())
+
+	rotated, err := os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, bytes.Count(rotated, []byte("\n")))
+	assert.Equal(t, 1, bytes.Count(current, []byte("\n")))
+}
+
+// TestDecisionLogRotateFailure tests that decisions keep being written to the current file if it can't be
+// moved aside, and that it is rotated once moving it works again.
+func TestDecisionLogRotateFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	// A non-empty directory in the way makes renaming the file fail.
+	require.NoError(t, os.MkdirAll(filepath.Join(path+".1", "blocker"), 0o755))
+
+	l, err := openDecisionLog(path, 100)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		l.write(DecisionTrace{Message: "decision"})
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, bytes.Count(data, []byte("\n")))
+	assert.True(t, l.rotateFailed)
+
+	require.NoError(t, os.RemoveAll(path+".1"))
+	l.write(DecisionTrace{Message: "decision"})
+	require.NoError(t, l.close())
+
+	rotated, err := os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, bytes.Count(rotated, []byte("\n")))
+}
@@ -0,0 +1,132 @@
+package slogenv_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// readDecisions returns the decisions written to the decision log at path.
+func readDecisions(t *testing.T, path string) []slogenv.DecisionTrace {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var decisions []slogenv.DecisionTrace
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var decision slogenv.DecisionTrace
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &decision))
+		decisions = append(decisions, decision)
+	}
+	require.NoError(t, scanner.Err())
+	return decisions
+}
+
+// TestDecisionLog tests that decisions are written to the decision log as JSON lines.
+func TestDecisionLog(t *testing.T) {
+	os.Setenv("GO_LOG", "warn,testpackage=debug")
+	defer os.Unsetenv("GO_LOG")
+
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h, slogenv.WithDecisionLog(path))
+	logger := slog.New(handler)
+	logger.Info("info")
+	logger.Warn("warn")
+	testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+	require.NoError(t, handler.Close())
+
+	assert.Equal(t, []string{"warn", "testpackage debug"}, h.Messages())
+
+	decisions := readDecisions(t, path)
+	require.Len(t, decisions, 3)
+
+	assert.Equal(t, "info", decisions[0].Message)
+	assert.Equal(t, "slog-env_test", decisions[0].Package)
+	assert.Equal(t, slog.LevelWarn, decisions[0].Threshold)
+	assert.False(t, decisions[0].Emitted)
+
+	assert.Equal(t, "warn", decisions[1].Message)
+	assert.True(t, decisions[1].Emitted)
+
+	assert.Equal(t, "testpackage debug", decisions[2].Message)
+	assert.Equal(t, "testpackage", decisions[2].Package)
+	assert.Equal(t, slog.LevelDebug, decisions[2].Level)
+	assert.Equal(t, slog.LevelDebug, decisions[2].Threshold)
+	assert.True(t, decisions[2].Emitted)
+}
+
+// TestDecisionLogMaxAttrs tests that records dropped for having too many attributes are logged as not emitted
+// and counted as dropped.
+func TestDecisionLogMaxAttrs(t *testing.T) {
+	t.Setenv("GO_LOG", "info,testpackage=debug")
+
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h,
+		slogenv.WithMaxAttrsForPackage("testpackage", 2),
+		slogenv.WithMaxAttrsPolicy(slogenv.AttrLimitDrop),
+		slogenv.WithDecisionLog(path),
+		slogenv.WithExpvar("slogenv_test_expvar_maxattrs"),
+	)
+	logger := slog.New(handler)
+	testpackage.LogAttrs(logger, slog.LevelInfo, "small", slog.Int("a", 1))
+	testpackage.LogAttrs(logger, slog.LevelInfo, "large", slog.Int("a", 1), slog.Int("b", 2), slog.Int("c", 3))
+	require.NoError(t, handler.Close())
+
+	assert.Equal(t, []string{"small"}, h.Messages())
+
+	decisions := readDecisions(t, path)
+	require.Len(t, decisions, 2)
+	assert.True(t, decisions[0].Emitted)
+	assert.Equal(t, "large", decisions[1].Message)
+	assert.False(t, decisions[1].Emitted)
+
+	emitted, dropped := expvarCounts(t, "slogenv_test_expvar_maxattrs")
+	assert.Equal(t, int64(1), emitted)
+	assert.Equal(t, int64(1), dropped)
+}
+
+// TestDecisionLogOutsideBand tests that records outside the band of filter levels are logged with their own
+// package and threshold.
+func TestDecisionLogOutsideBand(t *testing.T) {
+	t.Setenv("GO_LOG", "info,testpackage=debug")
+
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h, slogenv.WithDecisionLog(path))
+	logger := slog.New(handler)
+	logger.Log(context.Background(), slog.LevelDebug-4, "below")
+	logger.Error("above")
+	require.NoError(t, handler.Close())
+
+	decisions := readDecisions(t, path)
+	require.Len(t, decisions, 2)
+
+	assert.Equal(t, "below", decisions[0].Message)
+	assert.Equal(t, "slog-env_test", decisions[0].Package)
+	assert.Equal(t, slog.LevelInfo, decisions[0].Threshold)
+	assert.False(t, decisions[0].Emitted)
+
+	assert.Equal(t, "above", decisions[1].Message)
+	assert.Equal(t, "slog-env_test", decisions[1].Package)
+	assert.Equal(t, slog.LevelInfo, decisions[1].Threshold)
+	assert.True(t, decisions[1].Emitted)
+}
@@ -0,0 +1,100 @@
+package slogenv
+
+import (
+	"log/slog"
+	"sort"
+)
+
+// PackageLevel is the log level configured for a single package.
+type PackageLevel struct {
+	Package string
+	Level   slog.Level
+}
+
+// PackageLevelChange is a package whose log level differs between two filters.
+type PackageLevelChange struct {
+	Package string
+	Old     slog.Level
+	New     slog.Level
+}
+
+// FilterDiff describes the differences between two filters.
+// All package lists are sorted by package name.
+type FilterDiff struct {
+	// OldDefault is the default level of the first filter.
+	OldDefault slog.Level
+	// NewDefault is the default level of the second filter.
+	NewDefault slog.Level
+	// Added contains packages which are only present in the second filter.
+	Added []PackageLevel
+	// Removed contains packages which are only present in the first filter.
+	Removed []PackageLevel
+	// Changed contains packages present in both filters with different levels.
+	Changed []PackageLevelChange
+}
+
+// DefaultChanged reports whether the default level differs between the two filters.
+func (d FilterDiff) DefaultChanged() bool {
+	return d.OldDefault != d.NewDefault
+}
+
+// Empty reports whether the two filters are equivalent.
+func (d FilterDiff) Empty() bool {
+	return !d.DefaultChanged() && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffFilters compares two filter strings, such as an old and new value of GO_LOG.
+// Both filters are parsed with info as the default level, matching NewHandler.
+// An error is returned if either filter is malformed.
+func DiffFilters(a, b string) (FilterDiff, error) {
+	cfg := newConfig()
+
+	oldFilter, err := parseFilter(&cfg, a)
+	if err != nil {
+		return FilterDiff{}, err
+	}
+
+	newFilter, err := parseFilter(&cfg, b)
+	if err != nil {
+		return FilterDiff{}, err
+	}
+
+	return diffLevels(oldFilter.defaultLevel, oldFilter.perPackageLevel, newFilter.defaultLevel, newFilter.perPackageLevel), nil
+}
+
+// diffLevels compares the default and package levels of two filters.
+func diffLevels(oldDefault slog.Level, oldLevels map[string]slog.Level, newDefault slog.Level, newLevels map[string]slog.Level) FilterDiff {
+	diff := FilterDiff{
+		OldDefault: oldDefault,
+		NewDefault: newDefault,
+	}
+
+	for _, pkg := range sortedPackages(oldLevels) {
+		oldLevel := oldLevels[pkg]
+		newLevel, ok := newLevels[pkg]
+		switch {
+		case !ok:
+			diff.Removed = append(diff.Removed, PackageLevel{Package: pkg, Level: oldLevel})
+		case oldLevel != newLevel:
+			diff.Changed = append(diff.Changed, PackageLevelChange{Package: pkg, Old: oldLevel, New: newLevel})
+		}
+	}
+
+	for _, pkg := range sortedPackages(newLevels) {
+		if _, ok := oldLevels[pkg]; !ok {
+			diff.Added = append(diff.Added, PackageLevel{Package: pkg, Level: newLevels[pkg]})
+		}
+	}
+
+	return diff
+}
+
+// sortedPackages returns the keys of perPackageLevel in sorted order.
+func sortedPackages[V any](perPackageLevel map[string]V) []string {
+	pkgs := make([]string, 0, len(perPackageLevel))
+	for pkg := range perPackageLevel {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
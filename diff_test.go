@@ -0,0 +1,86 @@
+package slogenv_test
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	slogenv "github.com/cbrewster/slog-env"
+)
+
+// TestDiffFilters tests comparing two filters.
+func TestDiffFilters(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		a        string
+		b        string
+		wantDiff slogenv.FilterDiff
+	}{
+		{
+			name: "identical",
+			a:    "info,db=debug",
+			b:    "info,db=debug",
+			wantDiff: slogenv.FilterDiff{
+				OldDefault: slog.LevelInfo,
+				NewDefault: slog.LevelInfo,
+			},
+		},
+		{
+			name: "default changed",
+			a:    "info",
+			b:    "warn",
+			wantDiff: slogenv.FilterDiff{
+				OldDefault: slog.LevelInfo,
+				NewDefault: slog.LevelWarn,
+			},
+		},
+		{
+			name: "packages added removed and changed",
+			a:    "db=debug,cache=warn,http=info",
+			b:    "db=error,http=info,queue=debug,auth=warn",
+			wantDiff: slogenv.FilterDiff{
+				OldDefault: slog.LevelInfo,
+				NewDefault: slog.LevelInfo,
+				Added: []slogenv.PackageLevel{
+					{Package: "auth", Level: slog.LevelWarn},
+					{Package: "queue", Level: slog.LevelDebug},
+				},
+				Removed: []slogenv.PackageLevel{
+					{Package: "cache", Level: slog.LevelWarn},
+				},
+				Changed: []slogenv.PackageLevelChange{
+					{Package: "db", Old: slog.LevelDebug, New: slog.LevelError},
+				},
+			},
+		},
+		{
+			name: "empty filter",
+			a:    "",
+			b:    "error,db=debug",
+			wantDiff: slogenv.FilterDiff{
+				OldDefault: slog.LevelInfo,
+				NewDefault: slog.LevelError,
+				Added: []slogenv.PackageLevel{
+					{Package: "db", Level: slog.LevelDebug},
+				},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			diff, err := slogenv.DiffFilters(test.a, test.b)
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantDiff, diff)
+			assert.Equal(t, test.a == test.b, diff.Empty())
+		})
+	}
+}
+
+// TestDiffFiltersInvalid tests that malformed filters are reported.
+func TestDiffFiltersInvalid(t *testing.T) {
+	_, err := slogenv.DiffFilters("infoo", "info")
+	assert.EqualError(t, err, `invalid default level "infoo"`)
+
+	_, err = slogenv.DiffFilters("info", "db=loud")
+	assert.EqualError(t, err, `invalid level "loud" for package "db"`)
+}
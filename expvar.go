@@ -0,0 +1,52 @@
+package slogenv
+
+import (
+	"expvar"
+	"sync/atomic"
+)
+
+// WithExpvar publishes counts of emitted and dropped records, along with the handler's configuration,
+// as an [expvar] variable under name, so they are served on /debug/vars. Only records passed to Handle are
+// counted, since a call to Enabled returning false doesn't mean a record was logged. If a variable with
+// the same name is already published, the existing variable is left in place.
+func WithExpvar(name string) Opt {
+	return func(cfg *config) {
+		cfg.expvarName = name
+	}
+}
+
+// stats counts the records seen by a handler and all handlers derived from it.
+type stats struct {
+	emitted atomic.Int64
+	dropped atomic.Int64
+}
+
+// expvarStats is the structure published to expvar.
+type expvarStats struct {
+	Emitted int64             `json:"emitted"`
+	Dropped int64             `json:"dropped"`
+	Default string            `json:"default"`
+	Levels  map[string]string `json:"packages"`
+}
+
+// publishExpvar publishes the handler's stats under name, unless the name is already taken.
+func (h *Handler) publishExpvar(name string) {
+	if expvar.Get(name) != nil {
+		return
+	}
+
+	expvar.Publish(name, expvar.Func(func() any {
+		state := h.levels()
+		levels := make(map[string]string, len(state.perPackageLevel))
+		for pkg, level := range state.perPackageLevel {
+			levels[pkg] = formatLevel(level)
+		}
+
+		return expvarStats{
+			Emitted: h.stats.emitted.Load(),
+			Dropped: h.stats.dropped.Load(),
+			Default: formatLevel(state.defaultLevel),
+			Levels:  levels,
+		}
+	}))
+}
@@ -0,0 +1,124 @@
+package slogenv_test
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestExpvar tests that counters and configuration are published to expvar.
+func TestExpvar(t *testing.T) {
+	os.Setenv("GO_LOG", "warn,testpackage=debug")
+	defer os.Unsetenv("GO_LOG")
+
+	h := slogenvtest.NewCaptureHandler()
+	logger := slog.New(slogenv.NewHandler(h, slogenv.WithExpvar("slogenv_test_expvar")))
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.With("key", "value").Error("error")
+	testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+
+	v := expvar.Get("slogenv_test_expvar")
+	require.NotNil(t, v)
+
+	var published struct {
+		Emitted  int64             `json:"emitted"`
+		Dropped  int64             `json:"dropped"`
+		Default  string            `json:"default"`
+		Packages map[string]string `json:"packages"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(v.String()), &published))
+
+	assert.Equal(t, int64(3), published.Emitted)
+	assert.Equal(t, int64(2), published.Dropped)
+	assert.Equal(t, "WARN", published.Default)
+	assert.Equal(t, map[string]string{"testpackage": "DEBUG"}, published.Packages)
+
+	// Publishing under a taken name keeps the existing variable.
+	slogenv.NewHandler(h, slogenv.WithExpvar("slogenv_test_expvar"))
+	assert.Equal(t, v.String(), expvar.Get("slogenv_test_expvar").String())
+}
+
+// expvarCounts returns the emitted and dropped counters published to expvar under name.
+func expvarCounts(t *testing.T, name string) (emitted, dropped int64) {
+	t.Helper()
+
+	v := expvar.Get(name)
+	require.NotNil(t, v)
+
+	var published struct {
+		Emitted int64 `json:"emitted"`
+		Dropped int64 `json:"dropped"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(v.String()), &published))
+	return published.Emitted, published.Dropped
+}
+
+// TestExpvarSampling tests that records dropped by sampling are counted as dropped.
+func TestExpvarSampling(t *testing.T) {
+	t.Setenv("GO_LOG", "info,testpackage=debug")
+
+	h := slogenvtest.NewCaptureHandler()
+	logger := slog.New(slogenv.NewHandler(h,
+		slogenv.WithSampling("testpackage", 3),
+		slogenv.WithExpvar("slogenv_test_expvar_sampling"),
+	))
+	for i := 0; i < 6; i++ {
+		testpackage.LogSomething(logger, slog.LevelDebug, "debug")
+	}
+	logger.Debug("filtered")
+
+	emitted, dropped := expvarCounts(t, "slogenv_test_expvar_sampling")
+	assert.Len(t, h.Records(), 2)
+	assert.Equal(t, int64(2), emitted)
+	assert.Equal(t, int64(5), dropped)
+}
+
+// TestExpvarRateLimit tests that records dropped by rate limiting are counted as dropped.
+func TestExpvarRateLimit(t *testing.T) {
+	t.Setenv("GO_LOG", "debug")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h,
+		slogenv.WithRateLimit("testpackage", 5),
+		slogenv.WithExpvar("slogenv_test_expvar_ratelimit"),
+	)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 8; i++ {
+		record := testpackage.Record(slog.LevelDebug, "limited")
+		record.Time = start
+		require.NoError(t, handler.Handle(context.Background(), record))
+	}
+
+	emitted, dropped := expvarCounts(t, "slogenv_test_expvar_ratelimit")
+	assert.Len(t, h.Records(), 5)
+	assert.Equal(t, int64(5), emitted)
+	assert.Equal(t, int64(3), dropped)
+}
+
+// TestExpvarEnabled tests that checking whether a level is enabled doesn't count as dropping a record.
+func TestExpvarEnabled(t *testing.T) {
+	t.Setenv("GO_LOG", "warn")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(), slogenv.WithExpvar("slogenv_test_expvar_enabled"))
+	for i := 0; i < 3; i++ {
+		assert.False(t, handler.Enabled(context.Background(), slog.LevelInfo))
+	}
+	slog.New(handler).Warn("warn")
+
+	emitted, dropped := expvarCounts(t, "slogenv_test_expvar_enabled")
+	assert.Equal(t, int64(1), emitted)
+	assert.Equal(t, int64(0), dropped)
+}
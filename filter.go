@@ -0,0 +1,497 @@
+package slogenv
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// LevelOff is the level filters set for the off and none level names. It is above every level records
+// are logged at, so GO_LOG=info,noisypkg=off drops every record from noisypkg, including errors.
+const LevelOff = slog.Level(math.MaxInt)
+
+// WithLevelTranslation sets a function which translates level strings from other schemes, such as syslog
+// severities, into slog levels while parsing filters. If it returns false, the level is parsed as usual.
+func WithLevelTranslation(translate func(external string) (slog.Level, bool)) Opt {
+	return func(cfg *config) {
+		cfg.levelTranslation = translate
+	}
+}
+
+// OTelSeverity translates OpenTelemetry severity numbers from 1 to 24 into slog levels, and can be passed
+// to WithLevelTranslation to write filters such as GO_LOG=13,db=5. Each range of four severity numbers maps
+// onto a single slog level: TRACE (1-4) is slog.LevelDebug-4, DEBUG (5-8) is slog.LevelDebug, INFO (9-12) is
+// slog.LevelInfo, WARN (13-16) is slog.LevelWarn, ERROR (17-20) is slog.LevelError and FATAL (21-24) is
+// slog.LevelError+4.
+func OTelSeverity(external string) (slog.Level, bool) {
+	n, err := strconv.Atoi(external)
+	if err != nil || n < 1 || n > 24 {
+		return 0, false
+	}
+	return slog.LevelDebug - 4 + slog.Level((n-1)/4*4), true
+}
+
+// WithLevelNames registers additional level names, such as trace or fatal, which can be used in filters
+// alongside slog's own. Names are matched case-insensitively and accept the same offsets as slog's levels,
+// so with trace registered, GO_LOG=trace+2 is two levels above trace. Calling it again adds to the names.
+func WithLevelNames(names map[string]slog.Level) Opt {
+	return func(cfg *config) {
+		if cfg.levelNames == nil {
+			cfg.levelNames = make(map[string]slog.Level, len(names))
+		}
+		for name, level := range names {
+			cfg.levelNames[strings.ToLower(name)] = level
+		}
+	}
+}
+
+// WithLevelAliases registers alternative spellings for level names, such as warning for warn, err for error,
+// dbg for debug or information for info, to accept filters written for other logging libraries.
+// Aliases are matched case-insensitively and can stand for any level name, including ones registered
+// with WithLevelNames, and accept the same offsets, so with warning registered, GO_LOG=warning+2 is WARN+2.
+// Calling it again adds to the aliases.
+func WithLevelAliases(aliases map[string]string) Opt {
+	return func(cfg *config) {
+		if cfg.levelAliases == nil {
+			cfg.levelAliases = make(map[string]string, len(aliases))
+		}
+		for alias, name := range aliases {
+			cfg.levelAliases[strings.ToLower(alias)] = name
+		}
+	}
+}
+
+// WithTier sets the deployment tier, such as prod or dev, used to select tier-guarded filter segments.
+// A segment prefixed with a tier in brackets only applies when it matches the configured tier,
+// so GO_LOG=[prod]warn,[dev]debug,db=info sets the default to warn in prod and debug in dev,
+// while db=info applies in every tier. Guarded segments are ignored if no tier is set.
+func WithTier(tier string) Opt {
+	return func(cfg *config) {
+		cfg.tier = tier
+	}
+}
+
+// WithReversedSyntax allows package filters to also be written as level@package,
+// so GO_LOG=info,debug@db,warn@cache is equivalent to GO_LOG=info,db=debug,cache=warn.
+func WithReversedSyntax(enabled bool) Opt {
+	return func(cfg *config) {
+		cfg.reversedSyntax = enabled
+	}
+}
+
+// WithSeparator sets the separator between filter segments, for systems which already use commas
+// as list delimiters, so with WithSeparator("|"), GO_LOG=info|db=debug is equivalent to GO_LOG=info,db=debug.
+// Commas and semicolons are then part of segments rather than separating them, but newlines still separate
+// segments. The = between a package and its level is unchanged. An empty separator restores the default.
+func WithSeparator(sep string) Opt {
+	return func(cfg *config) {
+		cfg.separator = sep
+	}
+}
+
+// WithInheritDefaultFromPackages sets the default level to the most verbose package level when the filter
+// only contains package filters, such as GO_LOG=db=debug. By default, such filters keep the level from
+// WithDefaultLevel for all other packages.
+func WithInheritDefaultFromPackages(enabled bool) Opt {
+	return func(cfg *config) {
+		cfg.inheritDefaultFromPackages = enabled
+	}
+}
+
+// parsedFilter is the result of parsing a filter.
+type parsedFilter struct {
+	// defaultLevel is the level for packages without a filter.
+	defaultLevel slog.Level
+	// defaultSet is true if the filter contained a default level.
+	defaultSet bool
+	// perPackageLevel is the level for each package filter.
+	perPackageLevel map[string]slog.Level
+	// perPackageOffset is the offset from the default level for packages with a relative level.
+	// Their entry in perPackageLevel is kept resolved against the default level.
+	perPackageOffset map[string]slog.Level
+}
+
+// resolveOffsets updates the level of packages with a relative level to track the default level.
+func (p *parsedFilter) resolveOffsets() {
+	for pkg, offset := range p.perPackageOffset {
+		p.perPackageLevel[pkg] = offsetLevel(p.defaultLevel, offset)
+	}
+}
+
+// formatLevel formats a level for diagnostics, writing LevelOff as off rather than as a huge offset from error.
+func formatLevel(level slog.Level) string {
+	if level == LevelOff {
+		return "off"
+	}
+	return level.String()
+}
+
+// offsetLevel returns level adjusted by offset, keeping LevelOff silenced.
+func offsetLevel(level, offset slog.Level) slog.Level {
+	if level == LevelOff {
+		return LevelOff
+	}
+	return level + offset
+}
+
+// ParseFilter parses filter with the same syntax as the GO_LOG environment variable, returning the default level
+// and the level for each package filter. If the filter doesn't contain a default level, defaultLevel is returned.
+// Invalid segments are skipped and reported in the returned error, like Validate.
+// Use Validate instead to parse filters using options such as WithLevelNames.
+func ParseFilter(defaultLevel slog.Level, filter string) (slog.Level, map[string]slog.Level, error) {
+	cfg := newConfig(WithDefaultLevel(defaultLevel))
+	parsed, err := parseFilter(&cfg, filter)
+	return parsed.defaultLevel, parsed.perPackageLevel, err
+}
+
+// parseFilter parses the filter specified in the ENV var.
+// The filter can consist of comman separated filters.
+// A filter specifies a package and a filter level, if the package is omitted,
+// the filter level is used as the default.
+//
+// This will set the log level to info for all logs
+// GO_LOG=info
+//
+// This will set the log level to error by default, but debug for mypackage and info for otherpackage
+// GO_LOG=error,mypackage=debug,otherpackage=info
+//
+// Filters later in the list have higher precedence over ones earlier in the list, and a later filter for
+// the same package replaces an earlier one entirely, so GO_LOG=acme=debug,acme=info+2 sets acme to INFO+2.
+// If the filter doesn't contain a default level, the configured default level is kept.
+//
+// The default level can also be set explicitly with the default or * keys, which don't name a package.
+// This is equivalent to the filter above
+// GO_LOG=default=error,mypackage=debug,otherpackage=info
+//
+// A level made up of only + or - characters is relative to the default level, with each + making the
+// package one level more verbose and each - one level less verbose. Relative levels track the final default,
+// wherever it appears in the filter.
+// This will set the log level to debug for mypackage and error for otherpackage
+// GO_LOG=info,mypackage=+,otherpackage=--
+//
+// A package prefixed with - keeps the level from WithDefaultLevel, rather than the default level in the filter,
+// unless it is given a level of its own, which works as without the -.
+// This will set the log level to debug for all packages except chatty and vendor/thing, which stay at info
+// GO_LOG=debug,-chatty,-vendor/thing
+//
+// The levels off and none silence a package entirely, see LevelOff.
+// This will drop every log from noisypkg
+// GO_LOG=info,noisypkg=off
+//
+// A package can also be given by its full import path, and a package ending in * matches every package whose
+// import path starts with the rest of it, from the start of the path or of any element in it. Exact matches
+// take precedence, then the wildcard matching the most of the import path wins.
+// This will set the log level to debug for every package under github.com/acme/api
+// GO_LOG=info,acme/api/*=debug
+//
+// A package given by the path of the main module or one of its dependencies, as recorded in the build info,
+// also matches every package in the module, whichever major version it is at. Other full import paths only
+// match the package at that path.
+// This will set the log level to warn for golang.org/x/net, golang.org/x/net/http2 and the rest of the module
+// GO_LOG=info,golang.org/x/net=warn
+//
+// A package containing ?, [ or a * other than at its end is a glob, matched against the import path, or the end
+// of it following a slash, using path.Match. Globs are only consulted if no exact match or wildcard applies,
+// and the longest matching glob wins.
+// This will set the log level to debug for github.com/acme/v1/api and github.com/acme/v2/api
+// GO_LOG=info,acme/v[0-9]/api=debug
+//
+// Keys prefixed with group: match the group path of the logger instead of a package, see matchGroup.
+// This will set the log level to debug for logs in the http group and any groups nested in it
+// GO_LOG=info,group:http/*=debug
+//
+// Keys prefixed with attr: match the value of an attribute enabled with WithAttrFilter.
+// This will set the log level to debug for logs with a component attribute of billing
+// GO_LOG=info,attr:component=billing=debug
+//
+// Keys prefixed with func: or file: match the function or file a record was logged from, and take precedence
+// over package filters, see targetFilter.
+// This will set the log level to debug for logs from HandleLogin in acme/api and from server.go
+// GO_LOG=info,func:acme/api.HandleLogin=debug,file:server.go=debug
+//
+// A segment can be guarded by a deployment tier, see WithTier.
+// This will set the log level to warn in prod and debug in dev
+// GO_LOG=[prod]warn,[dev]debug
+//
+// Quotes around the whole filter, a segment, a package, or a level, which some shells and config formats
+// leave in place, are ignored, as is whitespace around them.
+//
+// Segments can also be separated by semicolons or newlines, so filters can be spread over several lines
+// in config files. Empty segments, such as from a trailing comma, are ignored. WithSeparator replaces
+// commas and semicolons with another separator.
+//
+// A # starts a comment which runs to the end of the segment, so segments can be annotated, or skipped by
+// starting them with #.
+// This will set the log level to info by default and debug for acme
+// GO_LOG="info, acme=debug # temporarily noisy"
+//
+// Segments with an invalid level are reported in the returned error, but do not stop
+// the rest of the filter from being parsed.
+func parseFilter(cfg *config, filter string) (parsedFilter, error) {
+	parsed := parsedFilter{
+		defaultLevel:     cfg.defaultLevel,
+		perPackageLevel:  make(map[string]slog.Level),
+		perPackageOffset: make(map[string]slog.Level),
+	}
+	var errs []error
+
+	filter = unquote(filter)
+	if filter == "" {
+		return parsed, nil
+	}
+
+	for _, filter := range cfg.splitSegments(filter) {
+		filter, _, _ = strings.Cut(filter, "#")
+		filter, applies := cfg.applyTierGuard(unquote(filter))
+		if !applies || filter == "" {
+			continue
+		}
+
+		// A package prefixed with - is excluded from the filter's default level.
+		pkg, excluded := strings.CutPrefix(filter, "-")
+		if excluded && strings.Trim(pkg, "+-") != "" {
+			filter = pkg
+		} else {
+			excluded = false
+		}
+
+		first, second, ok := cfg.splitSegment(filter)
+		if ok && !excluded && isDefaultKey(first) {
+			first, ok = second, false
+		}
+		if !ok && excluded {
+			parsed.perPackageLevel[first] = cfg.defaultLevel
+			delete(parsed.perPackageOffset, first)
+			continue
+		}
+		if !ok {
+			level, err := cfg.parseLevel(first)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid default level %q", first))
+				continue
+			}
+			parsed.defaultLevel = level
+			parsed.defaultSet = true
+			continue
+		}
+
+		if first == "" {
+			errs = append(errs, fmt.Errorf("empty package name in segment %q", filter))
+			continue
+		}
+		if err := checkPackagePattern(first); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if offset, ok := parseRelativeLevel(second); ok {
+			parsed.perPackageOffset[first] = offset
+			parsed.perPackageLevel[first] = 0
+			continue
+		}
+
+		level, err := cfg.parseLevel(second)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid level %q for package %q", second, first))
+			continue
+		}
+		delete(parsed.perPackageOffset, first)
+		parsed.perPackageLevel[first] = level
+	}
+
+	if !parsed.defaultSet && cfg.inheritDefaultFromPackages && len(parsed.perPackageLevel) > len(parsed.perPackageOffset) {
+		first := true
+		for pkg, level := range parsed.perPackageLevel {
+			if _, relative := parsed.perPackageOffset[pkg]; relative {
+				continue
+			}
+			if first || level < parsed.defaultLevel {
+				parsed.defaultLevel = level
+				first = false
+			}
+		}
+	}
+
+	parsed.resolveOffsets()
+
+	return parsed, errors.Join(errs...)
+}
+
+// ParseLevel parses a level the same way as levels in the handler's filter, including level names
+// registered with WithLevelNames and translated with WithLevelTranslation.
+func (h *Handler) ParseLevel(s string) (slog.Level, error) {
+	level, err := h.cfg.parseLevel(unquote(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid level %q", s)
+	}
+	return level, nil
+}
+
+// parseLevel parses a single level from a filter, consulting the level translation, the level aliases
+// and the custom level names before the off and none names and slog's level syntax.
+func (cfg *config) parseLevel(s string) (slog.Level, error) {
+	if cfg.levelTranslation != nil {
+		if level, ok := cfg.levelTranslation(s); ok {
+			return level, nil
+		}
+	}
+
+	s = cfg.resolveLevelAlias(s)
+
+	if level, ok := cfg.parseLevelName(s); ok {
+		return level, nil
+	}
+
+	if strings.EqualFold(s, "off") || strings.EqualFold(s, "none") {
+		return LevelOff, nil
+	}
+
+	var level slog.Level
+	err := level.UnmarshalText([]byte(s))
+	return level, err
+}
+
+// resolveLevelAlias replaces a level alias registered with WithLevelAliases with the name it stands for,
+// keeping any offset. Levels which aren't aliases are returned unchanged.
+func (cfg *config) resolveLevelAlias(s string) string {
+	if len(cfg.levelAliases) == 0 {
+		return s
+	}
+
+	name, offset := s, ""
+	if i := strings.IndexAny(s, "+-"); i > 0 {
+		name, offset = s[:i], s[i:]
+	}
+
+	if alias, ok := cfg.levelAliases[strings.ToLower(name)]; ok {
+		return alias + offset
+	}
+	return s
+}
+
+// parseLevelName parses a level registered with WithLevelNames, optionally followed by an offset like slog's levels.
+func (cfg *config) parseLevelName(s string) (slog.Level, bool) {
+	if len(cfg.levelNames) == 0 {
+		return 0, false
+	}
+
+	name, offset := s, ""
+	if i := strings.IndexAny(s, "+-"); i > 0 {
+		name, offset = s[:i], s[i:]
+	}
+
+	level, ok := cfg.levelNames[strings.ToLower(name)]
+	if !ok {
+		return 0, false
+	}
+	if offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil {
+			return 0, false
+		}
+		level += slog.Level(n)
+	}
+	return level, true
+}
+
+// levelStep is the distance between slog's standard levels.
+const levelStep = slog.LevelInfo - slog.LevelDebug
+
+// parseRelativeLevel parses a level relative to the default made up of + and - characters.
+// Each + is one step more verbose, and each - one step less verbose.
+func parseRelativeLevel(s string) (slog.Level, bool) {
+	if s == "" || strings.Trim(s, "+-") != "" {
+		return 0, false
+	}
+
+	offset := slog.Level(strings.Count(s, "-")-strings.Count(s, "+")) * levelStep
+	return offset, true
+}
+
+// isDefaultKey reports whether a segment's key sets the default level rather than naming a package.
+func isDefaultKey(key string) bool {
+	return key == "default" || key == "*"
+}
+
+// isSegmentSeparator reports whether r separates segments of a filter.
+func isSegmentSeparator(r rune) bool {
+	return r == ',' || r == ';' || r == '\n' || r == '\r'
+}
+
+// splitSegments splits a filter into its segments, at the configured separator and newlines.
+func (cfg *config) splitSegments(filter string) []string {
+	if cfg.separator == "" {
+		return strings.FieldsFunc(filter, isSegmentSeparator)
+	}
+
+	var segments []string
+	for _, line := range strings.FieldsFunc(filter, isLineSeparator) {
+		segments = append(segments, strings.Split(line, cfg.separator)...)
+	}
+	return segments
+}
+
+// isLineSeparator reports whether r ends a line of a filter.
+func isLineSeparator(r rune) bool {
+	return r == '\n' || r == '\r'
+}
+
+// segmentSeparator returns the separator to use when joining filters.
+func (cfg *config) segmentSeparator() string {
+	if cfg.separator == "" {
+		return ","
+	}
+	return cfg.separator
+}
+
+// splitSegment splits a filter segment into its package and level. If the segment has no package,
+// the level is returned as the package with ok set to false.
+func (cfg *config) splitSegment(segment string) (pkg, level string, ok bool) {
+	if isAttrKey(segment) {
+		// Attribute filter keys contain an = themselves, so the level follows the last one.
+		if i := strings.LastIndex(segment, "="); i > strings.Index(segment, "=") {
+			return unquote(segment[:i]), unquote(segment[i+1:]), true
+		}
+	}
+
+	pkg, level, ok = strings.Cut(segment, "=")
+	if !ok && cfg.reversedSyntax {
+		level, pkg, ok = strings.Cut(segment, "@")
+	}
+	if !ok {
+		return unquote(segment), "", false
+	}
+	return unquote(pkg), unquote(level), true
+}
+
+// applyTierGuard strips a [tier] guard from the start of a segment, reporting whether the segment
+// applies to the configured tier. Segments without a guard always apply.
+func (cfg *config) applyTierGuard(segment string) (string, bool) {
+	if !strings.HasPrefix(segment, "[") {
+		return segment, true
+	}
+
+	tier, rest, ok := strings.Cut(segment[1:], "]")
+	if !ok {
+		return segment, true
+	}
+	return strings.TrimSpace(rest), cfg.tier != "" && strings.TrimSpace(tier) == cfg.tier
+}
+
+// unquote trims surrounding whitespace and a pair of matching single or double quotes from s.
+// Quotes are only removed if they don't also appear inside s, so "a"="b" is left as is.
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || (s[0] != '"' && s[0] != '\'') || s[len(s)-1] != s[0] {
+		return s
+	}
+
+	inner := s[1 : len(s)-1]
+	if strings.IndexByte(inner, s[0]) >= 0 {
+		return s
+	}
+	return strings.TrimSpace(inner)
+}
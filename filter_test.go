@@ -0,0 +1,916 @@
+package slogenv_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/internal/testpackage/nested"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// syslogTranslation translates syslog severity names and numbers into slog levels.
+func syslogTranslation(external string) (slog.Level, bool) {
+	severities := map[string]slog.Level{
+		"emerg":   slog.LevelError + 4,
+		"alert":   slog.LevelError + 4,
+		"crit":    slog.LevelError + 2,
+		"err":     slog.LevelError,
+		"warning": slog.LevelWarn,
+		"notice":  slog.LevelInfo + 2,
+		"info":    slog.LevelInfo,
+		"debug":   slog.LevelDebug,
+	}
+	names := []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+	if n, err := strconv.Atoi(external); err == nil && n >= 0 && n < len(names) {
+		external = names[n]
+	}
+	level, ok := severities[strings.ToLower(external)]
+	return level, ok
+}
+
+// TestLevelTranslation tests translating levels from syslog severities.
+func TestLevelTranslation(t *testing.T) {
+	for _, test := range []struct {
+		filter       string
+		wantMessages []string
+	}{
+		{
+			filter:       "warning",
+			wantMessages: []string{"warn", "error"},
+		},
+		{
+			filter:       "4",
+			wantMessages: []string{"warn", "error"},
+		},
+		{
+			filter:       "3,testpackage=7",
+			wantMessages: []string{"error", "testpackage debug"},
+		},
+		{
+			filter:       "err,testpackage=notice",
+			wantMessages: []string{"error"},
+		},
+		{
+			// Levels the translation doesn't know are parsed as usual.
+			filter:       "warn+4,testpackage=DEBUG",
+			wantMessages: []string{"error", "testpackage debug"},
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			os.Setenv("GO_LOG", test.filter)
+			defer os.Unsetenv("GO_LOG")
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h, slogenv.WithLevelTranslation(syslogTranslation)))
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Warn("warn")
+			logger.Error("error")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+
+	assert.NoError(t, slogenv.Validate("warning,db=5", slogenv.WithLevelTranslation(syslogTranslation)))
+	assert.Error(t, slogenv.Validate("warning,db=5"))
+}
+
+// TestOTelSeverity tests translating levels from OpenTelemetry severity numbers.
+func TestOTelSeverity(t *testing.T) {
+	for _, test := range []struct {
+		filter       string
+		wantMessages []string
+	}{
+		{
+			filter:       "9",
+			wantMessages: []string{"info", "warn", "error"},
+		},
+		{
+			filter:       "5",
+			wantMessages: []string{"debug", "info", "warn", "error", "testpackage debug"},
+		},
+		{
+			filter:       "1",
+			wantMessages: []string{"debug", "info", "warn", "error", "testpackage debug"},
+		},
+		{
+			// Every severity number in a range maps onto the same level.
+			filter:       "12",
+			wantMessages: []string{"info", "warn", "error"},
+		},
+		{
+			filter:       "15",
+			wantMessages: []string{"warn", "error"},
+		},
+		{
+			filter:       "17,testpackage=5",
+			wantMessages: []string{"error", "testpackage debug"},
+		},
+		{
+			filter:       "21",
+			wantMessages: nil,
+		},
+		{
+			// Numbers outside the severity range are parsed as usual.
+			filter:       "warn,testpackage=debug+25",
+			wantMessages: []string{"warn", "error"},
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			os.Setenv("GO_LOG", test.filter)
+			defer os.Unsetenv("GO_LOG")
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h, slogenv.WithLevelTranslation(slogenv.OTelSeverity)))
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Warn("warn")
+			logger.Error("error")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+
+	for severity, want := range map[string]slog.Level{
+		"1":  slog.LevelDebug - 4,
+		"4":  slog.LevelDebug - 4,
+		"5":  slog.LevelDebug,
+		"8":  slog.LevelDebug,
+		"9":  slog.LevelInfo,
+		"10": slog.LevelInfo,
+		"12": slog.LevelInfo,
+		"13": slog.LevelWarn,
+		"16": slog.LevelWarn,
+		"17": slog.LevelError,
+		"20": slog.LevelError,
+		"21": slog.LevelError + 4,
+		"24": slog.LevelError + 4,
+	} {
+		level, ok := slogenv.OTelSeverity(severity)
+		assert.True(t, ok, severity)
+		assert.Equal(t, want, level, severity)
+	}
+	for _, severity := range []string{"0", "25", "-1", "info"} {
+		_, ok := slogenv.OTelSeverity(severity)
+		assert.False(t, ok, severity)
+	}
+}
+
+// TestLevelNames tests using custom level names in the default and package filters.
+func TestLevelNames(t *testing.T) {
+	const (
+		levelTrace = slog.Level(-8)
+		levelFatal = slog.Level(12)
+	)
+
+	for _, test := range []struct {
+		filter       string
+		wantMessages []string
+	}{
+		{
+			filter:       "trace",
+			wantMessages: []string{"trace", "debug", "info", "fatal", "testpackage trace"},
+		},
+		{
+			filter:       "FATAL,testpackage=Trace",
+			wantMessages: []string{"fatal", "testpackage trace"},
+		},
+		{
+			filter:       "trace+4,testpackage=fatal-4",
+			wantMessages: []string{"debug", "info", "fatal"},
+		},
+		{
+			// Built-in names still work alongside custom ones.
+			filter:       "info,testpackage=trace",
+			wantMessages: []string{"info", "fatal", "testpackage trace"},
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			t.Setenv("GO_LOG", test.filter)
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h, slogenv.WithLevelNames(map[string]slog.Level{
+				"TRACE": levelTrace,
+				"fatal": levelFatal,
+			})))
+			ctx := context.Background()
+			logger.Log(ctx, levelTrace, "trace")
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Log(ctx, levelFatal, "fatal")
+			testpackage.LogSomething(logger, levelTrace, "testpackage trace")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+
+	assert.Error(t, slogenv.Validate("trace"))
+	assert.Error(t, slogenv.Validate("trace+x", slogenv.WithLevelNames(map[string]slog.Level{"trace": levelTrace})))
+}
+
+// TestDefaultKey tests setting the default level with the default and * keys.
+func TestDefaultKey(t *testing.T) {
+	for _, test := range []struct {
+		filter       string
+		wantDefault  slog.Level
+		wantPackages map[string]slog.Level
+	}{
+		{
+			filter:       "default=warn,acme=debug",
+			wantDefault:  slog.LevelWarn,
+			wantPackages: map[string]slog.Level{"acme": slog.LevelDebug},
+		},
+		{
+			filter:       "*=error",
+			wantDefault:  slog.LevelError,
+			wantPackages: map[string]slog.Level{},
+		},
+		{
+			filter:       "warn,acme=debug",
+			wantDefault:  slog.LevelWarn,
+			wantPackages: map[string]slog.Level{"acme": slog.LevelDebug},
+		},
+		{
+			// The later default wins, whichever form it is written in.
+			filter:       "warn,default=debug",
+			wantDefault:  slog.LevelDebug,
+			wantPackages: map[string]slog.Level{},
+		},
+		{
+			filter:       "default=debug,error",
+			wantDefault:  slog.LevelError,
+			wantPackages: map[string]slog.Level{},
+		},
+		{
+			// Relative levels follow an explicit default.
+			filter:       "acme=+,default=warn",
+			wantDefault:  slog.LevelWarn,
+			wantPackages: map[string]slog.Level{"acme": slog.LevelInfo},
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			defaultLevel, packages, err := slogenv.ParseFilter(slog.LevelInfo, test.filter)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantDefault, defaultLevel)
+			assert.Equal(t, test.wantPackages, packages)
+		})
+	}
+
+	_, _, err := slogenv.ParseFilter(slog.LevelInfo, "default=loud")
+	assert.EqualError(t, err, `invalid default level "loud"`)
+}
+
+// TestLevelAliases tests using level aliases in the default and package filters.
+func TestLevelAliases(t *testing.T) {
+	aliases := slogenv.WithLevelAliases(map[string]string{
+		"warning":     "warn",
+		"ERR":         "error",
+		"dbg":         "debug",
+		"information": "info",
+		"verbose":     "trace",
+	})
+	names := slogenv.WithLevelNames(map[string]slog.Level{"trace": slog.Level(-8)})
+
+	for _, test := range []struct {
+		filter string
+		want   slog.Level
+	}{
+		{filter: "warning", want: slog.LevelWarn},
+		{filter: "WARNING", want: slog.LevelWarn},
+		{filter: "Err", want: slog.LevelError},
+		{filter: "dbg", want: slog.LevelDebug},
+		{filter: "information", want: slog.LevelInfo},
+		{filter: "warning+2", want: slog.LevelWarn + 2},
+		{filter: "err-1", want: slog.LevelError - 1},
+		{filter: "verbose", want: slog.Level(-8)},
+		// Names which aren't aliases are parsed as usual.
+		{filter: "debug", want: slog.LevelDebug},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			t.Setenv("GO_LOG", test.filter+",db="+test.filter)
+
+			handler, err := slogenv.NewHandlerWithError(slogenvtest.NewCaptureHandler(), aliases, names)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, handler.DefaultLevel())
+			assert.Equal(t, map[string]slog.Level{"db": test.want}, handler.PackageLevels())
+		})
+	}
+
+	t.Setenv("GO_LOG", "warning,testpackage=dbg")
+	h := slogenvtest.NewCaptureHandler()
+	logger := slog.New(slogenv.NewHandler(h, aliases))
+	logger.Info("info")
+	logger.Warn("warn")
+	testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+	assert.Equal(t, []string{"warn", "testpackage debug"}, h.Messages())
+
+	assert.Error(t, slogenv.Validate("warning"))
+	assert.Error(t, slogenv.Validate("verbose", aliases))
+}
+
+// TestLevelOff tests silencing packages with the off and none levels.
+func TestLevelOff(t *testing.T) {
+	for _, test := range []struct {
+		filter       string
+		opts         []slogenv.Opt
+		wantMessages []string
+		wantEnabled  bool
+	}{
+		{
+			filter:       "info,testpackage=off",
+			wantMessages: []string{"error"},
+			wantEnabled:  true,
+		},
+		{
+			filter:       "info,testpackage=NONE",
+			wantMessages: []string{"error"},
+			wantEnabled:  true,
+		},
+		{
+			filter:       "off,testpackage=error",
+			wantMessages: []string{"testpackage error", "testpackage beyond error"},
+			wantEnabled:  true,
+		},
+		{
+			// Relative levels don't bring a silenced default back.
+			filter:       "off,testpackage=-",
+			wantMessages: nil,
+			wantEnabled:  false,
+		},
+		{
+			// Custom level names take precedence.
+			filter:       "info,testpackage=off",
+			opts:         []slogenv.Opt{slogenv.WithLevelNames(map[string]slog.Level{"off": slog.LevelWarn})},
+			wantMessages: []string{"error", "testpackage error", "testpackage beyond error"},
+			wantEnabled:  true,
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			t.Setenv("GO_LOG", test.filter)
+
+			h := slogenvtest.NewCaptureHandler()
+			handler := slogenv.NewHandler(h, test.opts...)
+			logger := slog.New(handler)
+			logger.Error("error")
+			testpackage.LogSomething(logger, slog.LevelError, "testpackage error")
+			testpackage.LogSomething(logger, slog.LevelError+100, "testpackage beyond error")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+			assert.Equal(t, test.wantEnabled, handler.Enabled(context.Background(), slog.LevelError))
+		})
+	}
+}
+
+// TestParseFilter tests parsing filters into their default and package levels.
+func TestParseFilter(t *testing.T) {
+	for _, test := range []struct {
+		filter         string
+		wantDefault    slog.Level
+		wantPerPackage map[string]slog.Level
+		wantErr        string
+	}{
+		{
+			filter:         "",
+			wantDefault:    slog.LevelWarn,
+			wantPerPackage: map[string]slog.Level{},
+		},
+		{
+			filter:         "debug,db=error,cache=info+2,group:http=warn",
+			wantDefault:    slog.LevelDebug,
+			wantPerPackage: map[string]slog.Level{"db": slog.LevelError, "cache": slog.LevelInfo + 2, "group:http": slog.LevelWarn},
+		},
+		{
+			filter:         "db=debug,db=error,cache=+",
+			wantDefault:    slog.LevelWarn,
+			wantPerPackage: map[string]slog.Level{"db": slog.LevelError, "cache": slog.LevelInfo},
+		},
+		{
+			filter:         "loud,db=debug",
+			wantDefault:    slog.LevelWarn,
+			wantPerPackage: map[string]slog.Level{"db": slog.LevelDebug},
+			wantErr:        `invalid default level "loud"`,
+		},
+		{
+			filter:         "info,db=debugg,=warn,cache=error",
+			wantDefault:    slog.LevelInfo,
+			wantPerPackage: map[string]slog.Level{"cache": slog.LevelError},
+			wantErr:        "invalid level \"debugg\" for package \"db\"\nempty package name in segment \"=warn\"",
+		},
+		{
+			// A package with an invalid level is skipped rather than set to a more verbose level than the default.
+			filter:         "error,mypkg=infoo",
+			wantDefault:    slog.LevelError,
+			wantPerPackage: map[string]slog.Level{},
+			wantErr:        `invalid level "infoo" for package "mypkg"`,
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			defaultLevel, perPackage, err := slogenv.ParseFilter(slog.LevelWarn, test.filter)
+			if test.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.wantErr)
+			}
+			assert.Equal(t, test.wantDefault, defaultLevel)
+			assert.Equal(t, test.wantPerPackage, perPackage)
+		})
+	}
+}
+
+// TestHandlerParseLevel tests parsing levels with the handler's options.
+func TestHandlerParseLevel(t *testing.T) {
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(), slogenv.WithLevelNames(map[string]slog.Level{"trace": -8}))
+
+	for s, want := range map[string]slog.Level{
+		"debug":   slog.LevelDebug,
+		"warn+2":  slog.LevelWarn + 2,
+		"trace+2": slog.Level(-6),
+		`"off"`:   slogenv.LevelOff,
+	} {
+		level, err := handler.ParseLevel(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, want, level, s)
+	}
+
+	_, err := handler.ParseLevel("loud")
+	assert.EqualError(t, err, `invalid level "loud"`)
+}
+
+// TestExcludedPackages tests that packages prefixed with - keep the configured default level.
+func TestExcludedPackages(t *testing.T) {
+	for _, test := range []struct {
+		filter       string
+		opts         []slogenv.Opt
+		wantMessages []string
+	}{
+		{
+			filter:       "debug,-testpackage",
+			wantMessages: []string{"debug", "nested debug", "testpackage info", "nested info"},
+		},
+		{
+			filter:       "debug,-testpackage,-nested",
+			wantMessages: []string{"debug", "testpackage info", "nested info"},
+		},
+		{
+			filter:       "debug,-testpackage=warn",
+			wantMessages: []string{"debug", "nested debug", "nested info"},
+		},
+		{
+			filter:       "debug,-testpackage",
+			opts:         []slogenv.Opt{slogenv.WithDefaultLevel(slog.LevelError)},
+			wantMessages: []string{"debug", "nested debug", "nested info"},
+		},
+		{
+			// Later segments still take precedence.
+			filter:       "debug,-testpackage,testpackage=debug",
+			wantMessages: []string{"debug", "testpackage debug", "nested debug", "testpackage info", "nested info"},
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			t.Setenv("GO_LOG", test.filter)
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h, test.opts...))
+			logger.Debug("debug")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+			nested.LogSomething(logger, slog.LevelDebug, "nested debug")
+			testpackage.LogSomething(logger, slog.LevelInfo, "testpackage info")
+			nested.LogSomething(logger, slog.LevelInfo, "nested info")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+
+	assert.NoError(t, slogenv.Validate("info,-db,-cache=warn,db=-"))
+	assert.EqualError(t, slogenv.Validate("-=warn"), `empty package name in segment "=warn"`)
+}
+
+// TestFilterWhitespace tests that whitespace, other separators and empty segments are tolerated.
+func TestFilterWhitespace(t *testing.T) {
+	want := map[string]slog.Level{"testpackage": slog.LevelDebug, "db": slog.LevelError}
+
+	for _, filter := range []string{
+		"warn, testpackage=debug, db=error",
+		"warn,testpackage = debug,db\t=\terror",
+		"\twarn ,testpackage=debug,,db=error,",
+		",warn;testpackage=debug;db=error;",
+		"warn\ntestpackage=debug\ndb=error\n",
+		"warn\r\n  testpackage=debug\r\n  db=error\r\n",
+		"\n  warn\n  testpackage=debug\n  \"db=error\"\n",
+	} {
+		t.Run(filter, func(t *testing.T) {
+			defaultLevel, perPackage, err := slogenv.ParseFilter(slog.LevelInfo, filter)
+			require.NoError(t, err)
+			assert.Equal(t, slog.LevelWarn, defaultLevel)
+			assert.Equal(t, want, perPackage)
+		})
+	}
+
+	assert.NoError(t, slogenv.Validate(" , ;\n"))
+}
+
+// TestLevelOffsets tests levels with offsets, such as info+2, for the default and for packages.
+func TestLevelOffsets(t *testing.T) {
+	for _, test := range []struct {
+		filter      string
+		wantDefault slog.Level
+		wantAcme    slog.Level
+	}{
+		{filter: "info+2,acme=error-4", wantDefault: 2, wantAcme: 4},
+		{filter: "INFO+2,acme=ERROR-4", wantDefault: 2, wantAcme: 4},
+		{filter: "Info+2,acme=Error-4", wantDefault: 2, wantAcme: 4},
+		{filter: "debug-4,acme=debug+1", wantDefault: -8, wantAcme: -3},
+		{filter: "warn+0,acme=warn-0", wantDefault: 4, wantAcme: 4},
+		{filter: "error+10,acme=info+12", wantDefault: 18, wantAcme: 12},
+		{filter: "acme=warn-2,info-1", wantDefault: -1, wantAcme: 2},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			defaultLevel, perPackage, err := slogenv.ParseFilter(slog.LevelWarn, test.filter)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantDefault, defaultLevel)
+			assert.Equal(t, map[string]slog.Level{"acme": test.wantAcme}, perPackage)
+		})
+	}
+
+	for _, filter := range []string{"info+", "info+x", "acme=error-", "acme=error--4"} {
+		_, _, err := slogenv.ParseFilter(slog.LevelInfo, filter)
+		assert.Error(t, err, filter)
+	}
+}
+
+// TestRepeatedPackage tests that a later filter for the same package fully replaces an earlier one,
+// whether either is absolute, relative to the default, or an exclusion.
+func TestRepeatedPackage(t *testing.T) {
+	for _, test := range []struct {
+		filter  string
+		want    slog.Level
+		wantErr string
+	}{
+		{filter: "acme=debug,acme=info+2", want: slog.LevelInfo + 2},
+		{filter: "acme=info+2,acme=debug", want: slog.LevelDebug},
+		{filter: "acme=warn+1,acme=warn+1", want: slog.LevelWarn + 1},
+		{filter: "warn,acme=+,acme=error", want: slog.LevelError},
+		{filter: "warn,acme=error,acme=+", want: slog.LevelInfo},
+		{filter: "warn,acme=+,acme=--", want: slog.LevelError + 4},
+		{filter: "warn,acme=debug,-acme", want: slog.LevelInfo},
+		{filter: "warn,-acme,acme=error", want: slog.LevelError},
+		{filter: "acme=off,acme=debug", want: slog.LevelDebug},
+		// An invalid level leaves the earlier filter in place.
+		{filter: "acme=debug,acme=loud", want: slog.LevelDebug, wantErr: `invalid level "loud" for package "acme"`},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			_, perPackage, err := slogenv.ParseFilter(slog.LevelInfo, test.filter)
+			if test.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.wantErr)
+			}
+			assert.Equal(t, map[string]slog.Level{"acme": test.want}, perPackage)
+		})
+	}
+}
+
+// TestFilterComments tests that comments are ignored, whether after a segment or in place of one.
+func TestFilterComments(t *testing.T) {
+	for _, test := range []struct {
+		filter string
+		want   map[string]slog.Level
+	}{
+		{
+			filter: "warn, testpackage=debug # temporarily noisy",
+			want:   map[string]slog.Level{"testpackage": slog.LevelDebug},
+		},
+		{
+			filter: "warn,testpackage=debug#no space",
+			want:   map[string]slog.Level{"testpackage": slog.LevelDebug},
+		},
+		{
+			filter: "# Levels for local development\nwarn\ntestpackage=debug # noisy but useful\n#db=error\n",
+			want:   map[string]slog.Level{"testpackage": slog.LevelDebug},
+		},
+		{
+			filter: "warn\r\n  # db=error\r\n  testpackage=debug\r\n",
+			want:   map[string]slog.Level{"testpackage": slog.LevelDebug},
+		},
+		{
+			filter: `"warn,testpackage=debug # quoted"`,
+			want:   map[string]slog.Level{"testpackage": slog.LevelDebug},
+		},
+		{
+			filter: "# testpackage=debug\nwarn",
+			want:   map[string]slog.Level{},
+		},
+		{
+			// Comments only run to the end of their segment.
+			filter: "warn,#testpackage=debug,db=error",
+			want:   map[string]slog.Level{"db": slog.LevelError},
+		},
+		{
+			filter: "warn,testpackage=debug # noisy,db=error",
+			want:   map[string]slog.Level{"testpackage": slog.LevelDebug, "db": slog.LevelError},
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			defaultLevel, perPackage, err := slogenv.ParseFilter(slog.LevelInfo, test.filter)
+			require.NoError(t, err)
+			assert.Equal(t, slog.LevelWarn, defaultLevel)
+			assert.Equal(t, test.want, perPackage)
+		})
+	}
+}
+
+// TestQuotedFilter tests that quotes and whitespace around levels are ignored.
+func TestQuotedFilter(t *testing.T) {
+	for _, test := range []struct {
+		filter       string
+		wantMessages []string
+	}{
+		{
+			filter:       `"debug"`,
+			wantMessages: []string{"debug", "info", "warn", "testpackage debug"},
+		},
+		{
+			filter:       `'warn'`,
+			wantMessages: []string{"warn"},
+		},
+		{
+			filter:       "debug ",
+			wantMessages: []string{"debug", "info", "warn", "testpackage debug"},
+		},
+		{
+			filter:       `"warn,testpackage=debug"`,
+			wantMessages: []string{"warn", "testpackage debug"},
+		},
+		{
+			filter:       `"warn","testpackage"="debug"`,
+			wantMessages: []string{"warn", "testpackage debug"},
+		},
+		{
+			filter:       `warn,"testpackage=debug"`,
+			wantMessages: []string{"warn", "testpackage debug"},
+		},
+		{
+			filter:       `warn,testpackage="debug"`,
+			wantMessages: []string{"warn", "testpackage debug"},
+		},
+		{
+			filter:       `warn,testpackage='debug' `,
+			wantMessages: []string{"warn", "testpackage debug"},
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			os.Setenv("GO_LOG", test.filter)
+			defer os.Unsetenv("GO_LOG")
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h))
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Warn("warn")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
+
+// TestTier tests that tier-guarded segments only apply to the configured tier.
+func TestTier(t *testing.T) {
+	for _, test := range []struct {
+		tier         string
+		wantMessages []string
+	}{
+		{
+			tier:         "prod",
+			wantMessages: []string{"warn", "testpackage info"},
+		},
+		{
+			tier:         "dev",
+			wantMessages: []string{"debug", "info", "warn", "testpackage debug", "testpackage info"},
+		},
+		{
+			tier:         "staging",
+			wantMessages: []string{"info", "warn", "testpackage info"},
+		},
+		{
+			tier:         "",
+			wantMessages: []string{"info", "warn", "testpackage info"},
+		},
+	} {
+		t.Run(test.tier, func(t *testing.T) {
+			os.Setenv("GO_LOG", "[prod]warn,[dev]debug,testpackage=info,[dev]testpackage=debug")
+			defer os.Unsetenv("GO_LOG")
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h, slogenv.WithTier(test.tier)))
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Warn("warn")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+			testpackage.LogSomething(logger, slog.LevelInfo, "testpackage info")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
+
+// TestReversedSyntax tests that level@package filters are equivalent to package=level filters.
+func TestReversedSyntax(t *testing.T) {
+	for _, test := range []struct {
+		reversed string
+		standard string
+	}{
+		{reversed: "debug@testpackage", standard: "testpackage=debug"},
+		{reversed: "warn,debug@testpackage,error@db", standard: "warn,testpackage=debug,db=error"},
+		{reversed: "warn,debug@testpackage,cache=info", standard: "warn,testpackage=debug,cache=info"},
+	} {
+		t.Run(test.reversed, func(t *testing.T) {
+			var handlers []*slogenv.Handler
+			var messages [][]string
+			for _, filter := range []string{test.reversed, test.standard} {
+				os.Setenv("GO_LOG", filter)
+
+				h := slogenvtest.NewCaptureHandler()
+				handler := slogenv.NewHandler(h, slogenv.WithReversedSyntax(true))
+				logger := slog.New(handler)
+				logger.Info("info")
+				logger.Warn("warn")
+				testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+
+				handlers = append(handlers, handler)
+				messages = append(messages, h.Messages())
+			}
+			os.Unsetenv("GO_LOG")
+
+			assert.Equal(t, handlers[1].EffectiveLevels(), handlers[0].EffectiveLevels())
+			assert.Equal(t, messages[1], messages[0])
+			assert.Contains(t, messages[0], "testpackage debug")
+		})
+	}
+
+	assert.Error(t, slogenv.Validate("debug@testpackage"))
+	assert.NoError(t, slogenv.Validate("debug@testpackage", slogenv.WithReversedSyntax(true)))
+}
+
+// TestSeparator tests that filters using a custom separator are equivalent to comma-separated filters.
+func TestSeparator(t *testing.T) {
+	for _, test := range []struct {
+		separator string
+		filter    string
+		standard  string
+	}{
+		{separator: ";", filter: "warn;testpackage=debug;db=error", standard: "warn,testpackage=debug,db=error"},
+		{separator: "|", filter: "warn|testpackage=debug|db=error", standard: "warn,testpackage=debug,db=error"},
+		{separator: "|", filter: "warn|testpackage=debug\ndb=error|", standard: "warn,testpackage=debug,db=error"},
+		{separator: " ", filter: "warn testpackage=debug", standard: "warn,testpackage=debug"},
+		{separator: "", filter: "warn;testpackage=debug", standard: "warn,testpackage=debug"},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			var handlers []*slogenv.Handler
+			var messages [][]string
+			for _, opt := range []slogenv.Opt{slogenv.WithSeparator(test.separator), slogenv.WithSeparator("")} {
+				filter := test.standard
+				if len(handlers) == 0 {
+					filter = test.filter
+				}
+				t.Setenv("GO_LOG", filter)
+
+				h := slogenvtest.NewCaptureHandler()
+				handler, err := slogenv.NewHandlerWithError(h, opt)
+				require.NoError(t, err)
+				logger := slog.New(handler)
+				logger.Info("info")
+				logger.Warn("warn")
+				testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+
+				handlers = append(handlers, handler)
+				messages = append(messages, h.Messages())
+			}
+
+			assert.Equal(t, handlers[1].EffectiveLevels(), handlers[0].EffectiveLevels())
+			assert.Equal(t, handlers[1].DefaultLevel(), handlers[0].DefaultLevel())
+			assert.Equal(t, messages[1], messages[0])
+		})
+	}
+
+	// With a custom separator, commas are part of the segment.
+	assert.Error(t, slogenv.Validate("debug,warn", slogenv.WithSeparator("|")))
+}
+
+// TestPackageOnlyFilter tests the default level when the filter only contains package filters.
+func TestPackageOnlyFilter(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		filter       string
+		opts         []slogenv.Opt
+		wantMessages []string
+	}{
+		{
+			name:         "keeps default level",
+			filter:       "testpackage=debug",
+			wantMessages: []string{"info", "warn", "testpackage debug"},
+		},
+		{
+			name:         "keeps configured default level",
+			filter:       "testpackage=debug",
+			opts:         []slogenv.Opt{slogenv.WithDefaultLevel(slog.LevelWarn)},
+			wantMessages: []string{"warn", "testpackage debug"},
+		},
+		{
+			name:         "inherits most verbose package level",
+			filter:       "testpackage=debug,db=warn",
+			opts:         []slogenv.Opt{slogenv.WithInheritDefaultFromPackages(true)},
+			wantMessages: []string{"debug", "info", "warn", "testpackage debug"},
+		},
+		{
+			name:         "explicit default wins over inheritance",
+			filter:       "warn,testpackage=debug",
+			opts:         []slogenv.Opt{slogenv.WithInheritDefaultFromPackages(true)},
+			wantMessages: []string{"warn", "testpackage debug"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			os.Setenv("GO_LOG", test.filter)
+			defer os.Unsetenv("GO_LOG")
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h, test.opts...))
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Warn("warn")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
+
+// TestRelativeLevel tests package levels relative to the default level.
+func TestRelativeLevel(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		filter       string
+		opts         []slogenv.Opt
+		wantMessages []string
+	}{
+		{
+			name:         "one step more verbose",
+			filter:       "info,testpackage=+",
+			wantMessages: []string{"info", "warn", "testpackage debug", "testpackage info", "testpackage warn"},
+		},
+		{
+			name:         "tracks default from filter",
+			filter:       "testpackage=+,warn",
+			wantMessages: []string{"warn", "testpackage info", "testpackage warn"},
+		},
+		{
+			name:         "tracks configured default",
+			filter:       "testpackage=+",
+			opts:         []slogenv.Opt{slogenv.WithDefaultLevel(slog.LevelError)},
+			wantMessages: []string{"testpackage warn"},
+		},
+		{
+			name:         "less verbose",
+			filter:       "debug,testpackage=-",
+			wantMessages: []string{"debug", "info", "warn", "testpackage info", "testpackage warn"},
+		},
+		{
+			name:         "several steps",
+			filter:       "warn,testpackage=++",
+			wantMessages: []string{"warn", "testpackage debug", "testpackage info", "testpackage warn"},
+		},
+		{
+			name:         "later absolute level wins",
+			filter:       "info,testpackage=+,testpackage=warn",
+			wantMessages: []string{"info", "warn", "testpackage warn"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			os.Setenv("GO_LOG", test.filter)
+			defer os.Unsetenv("GO_LOG")
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h, test.opts...))
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Warn("warn")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+			testpackage.LogSomething(logger, slog.LevelInfo, "testpackage info")
+			testpackage.LogSomething(logger, slog.LevelWarn, "testpackage warn")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
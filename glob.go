@@ -0,0 +1,83 @@
+package slogenv
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+)
+
+// packageGlob is a package filter containing glob metacharacters, such as acme/v?/api=debug, which matches
+// packages whose full import path, or the end of it following a slash, matches the pattern using path.Match.
+// As in path.Match, * and ? don't match slashes.
+type packageGlob struct {
+	key   string
+	level slog.Level
+}
+
+// packageGlobs returns the glob filters in perPackageLevel, longest pattern first so the most specific
+// pattern is checked first, with ties broken by the pattern itself.
+func packageGlobs(perPackageLevel map[string]slog.Level) []packageGlob {
+	var globs []packageGlob
+	for key, level := range perPackageLevel {
+		if isGlobKey(key) {
+			globs = append(globs, packageGlob{key: key, level: level})
+		}
+	}
+
+	sort.Slice(globs, func(i, j int) bool {
+		if len(globs[i].key) != len(globs[j].key) {
+			return len(globs[i].key) > len(globs[j].key)
+		}
+		return globs[i].key < globs[j].key
+	})
+	return globs
+}
+
+// match reports whether the glob matches the full import path or the end of it following a slash.
+func (g packageGlob) match(pkgPath string) bool {
+	if pkgPath == "" {
+		return false
+	}
+	for {
+		if ok, _ := path.Match(g.key, pkgPath); ok {
+			return true
+		}
+		i := strings.Index(pkgPath, "/")
+		if i < 0 {
+			return false
+		}
+		pkgPath = pkgPath[i+1:]
+	}
+}
+
+// matchGlob returns the first glob in s matching the full import path.
+func (s *levelState) matchGlob(pkgPath string) (packageGlob, bool) {
+	for _, glob := range s.globs {
+		if glob.match(pkgPath) {
+			return glob, true
+		}
+	}
+	return packageGlob{}, false
+}
+
+// isGlobKey reports whether a filter key is a package glob. A * at the end of a key on its own
+// makes it a prefix wildcard rather than a glob.
+func isGlobKey(key string) bool {
+	if strings.HasPrefix(key, groupPrefix) || isTargetKey(key) || isAttrKey(key) {
+		return false
+	}
+	return strings.ContainsAny(strings.TrimSuffix(key, "*"), "*?[")
+}
+
+// checkPackagePattern returns an error if key is a glob which isn't a valid pattern.
+func checkPackagePattern(key string) error {
+	if !isGlobKey(key) {
+		return nil
+	}
+	if _, err := path.Match(key, ""); err != nil {
+		return fmt.Errorf("invalid package pattern %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package slogenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPackageGlobMatch tests matching globs against import paths.
+func TestPackageGlobMatch(t *testing.T) {
+	for _, test := range []struct {
+		key  string
+		path string
+		want bool
+	}{
+		{key: "acme/v?/api", path: "github.com/acme/v2/api", want: true},
+		{key: "acme/v?/api", path: "github.com/acme/v10/api", want: false},
+		{key: "acme/v[0-9]/api", path: "github.com/acme/v3/api", want: true},
+		{key: "acme/v[0-9]/api", path: "github.com/acme/vx/api", want: false},
+		{key: "acme/v[0-9]*/api", path: "github.com/acme/v10/api", want: true},
+		{key: "acme/*/api", path: "github.com/acme/v2/api", want: true},
+		{key: "acme/*/api", path: "github.com/acme/v2/internal/api", want: false},
+		{key: "acme/*/api", path: "github.com/notacme/v2/api", want: false},
+		{key: "github.com/*/v?/api", path: "github.com/acme/v2/api", want: true},
+		{key: "api?", path: "github.com/acme/api2", want: true},
+		{key: "api?", path: "github.com/acme/api2/client", want: false},
+		{key: "acme/v?/api", path: "", want: false},
+	} {
+		glob := packageGlob{key: test.key}
+		assert.Equal(t, test.want, glob.match(test.path), "%s against %s", test.key, test.path)
+	}
+}
+
+// TestIsGlobKey tests telling globs apart from other filter keys.
+func TestIsGlobKey(t *testing.T) {
+	for key, want := range map[string]bool{
+		"acme/v?/api":     true,
+		"acme/[ab]pi":     true,
+		"acme/*/api":      true,
+		"acme/v?/*":       true,
+		"acme/api/*":      false,
+		"acme/api":        false,
+		"group:http/?":    false,
+		"func:acme.Get*s": false,
+	} {
+		assert.Equal(t, want, isGlobKey(key), key)
+	}
+}
@@ -0,0 +1,81 @@
+package slogenv_test
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	slogenv "github.com/cbrewster/slog-env"
+	shadowtestpackage "github.com/cbrewster/slog-env/internal/shadow/testpackage"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/internal/testpackage/nested"
+	"github.com/cbrewster/slog-env/internal/testpackage/nested/deeper"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestGlobFilters tests package filters containing glob metacharacters.
+func TestGlobFilters(t *testing.T) {
+	for _, test := range []struct {
+		filter       string
+		wantMessages []string
+	}{
+		{
+			filter:       "info,test?ackage=debug",
+			wantMessages: []string{"testpackage", "shadow"},
+		},
+		{
+			filter:       "info,internal/*/testpackage=debug",
+			wantMessages: []string{"shadow"},
+		},
+		{
+			filter:       "info,testpackage/ne[rs]ted=debug",
+			wantMessages: []string{"nested"},
+		},
+		{
+			// A * followed by more of the pattern doesn't match across slashes.
+			filter:       "info,testpackage/*/deeper=debug",
+			wantMessages: []string{"deeper"},
+		},
+		{
+			// A trailing * after another metacharacter is part of the glob, so it doesn't match subpackages.
+			filter:       "info,testpackage/[n-z]*=debug",
+			wantMessages: []string{"nested"},
+		},
+		{
+			filter:       "info,s?ad[a-z]w/*=debug",
+			wantMessages: []string{"shadow"},
+		},
+		{
+			// Exact matches take precedence over globs.
+			filter:       "info,*/nested=debug,nested=warn",
+			wantMessages: nil,
+		},
+		{
+			// Prefix wildcards take precedence over globs.
+			filter:       "info,internal/testpackage/*=warn,testpackage/n?sted=debug",
+			wantMessages: nil,
+		},
+		{
+			// The longest matching glob wins.
+			filter:       "info,*/nested=warn,testpackage/n?sted=debug,internal/te*/deeper=warn",
+			wantMessages: []string{"nested"},
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			t.Setenv("GO_LOG", test.filter)
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h))
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage")
+			nested.LogSomething(logger, slog.LevelDebug, "nested")
+			deeper.LogSomething(logger, slog.LevelDebug, "deeper")
+			shadowtestpackage.LogSomething(logger, slog.LevelDebug, "shadow")
+			logger.Debug("local")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+
+	assert.EqualError(t, slogenv.Validate("info,acme/[v=debug"), `invalid package pattern "acme/[v"`)
+}
@@ -0,0 +1,42 @@
+package slogenv
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// groupPrefix marks filter keys which match group paths rather than packages.
+const groupPrefix = "group:"
+
+// matchGroup returns the key and level of the group filter in perPackageLevel matching the group path, if any.
+// Group paths are the names passed to WithGroup joined by slashes, such as http/api.
+// A filter such as group:http/api matches exactly that path, while group:http/api/* also matches
+// any group nested within it. Exact matches take precedence, then the longest matching prefix wins.
+func matchGroup(perPackageLevel map[string]slog.Level, groups []string) (string, slog.Level) {
+	path := strings.Join(groups, "/")
+
+	var bestRule string
+	var bestLevel slog.Level
+	bestLen := -1
+	for key, level := range perPackageLevel {
+		pattern, ok := strings.CutPrefix(key, groupPrefix)
+		if !ok {
+			continue
+		}
+
+		if pattern == path {
+			return key, level
+		}
+
+		base, isPrefix := strings.CutSuffix(pattern, "/*")
+		if !isPrefix || (path != base && !strings.HasPrefix(path, base+"/")) {
+			continue
+		}
+
+		if len(base) > bestLen || (len(base) == bestLen && key < bestRule) {
+			bestRule, bestLevel, bestLen = key, level, len(base)
+		}
+	}
+
+	return bestRule, bestLevel
+}
@@ -0,0 +1,91 @@
+package slogenv_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestGroupPrefixFilter tests filtering by group path prefixes.
+func TestGroupPrefixFilter(t *testing.T) {
+	os.Setenv("GO_LOG", "warn,group:http/*=info,group:http/api/*=debug,group:http/api/health/*=error")
+	defer os.Unsetenv("GO_LOG")
+
+	h := slogenvtest.NewCaptureHandler()
+	logger := slog.New(slogenv.NewHandler(h))
+
+	logger.Info("root info")
+
+	http := logger.WithGroup("http")
+	http.Debug("http debug")
+	http.Info("http info")
+
+	api := http.WithGroup("api")
+	api.Debug("api debug")
+
+	users := api.WithGroup("users")
+	users.Debug("users debug")
+
+	health := api.WithGroup("health")
+	health.Warn("health warn")
+	health.Error("health error")
+
+	// Group filters take precedence over package filters.
+	testpackage.LogSomething(api, slog.LevelDebug, "testpackage api debug")
+
+	// Groups outside of the filtered tree use the default.
+	other := logger.WithGroup("httpx")
+	other.Info("httpx info")
+
+	assert.Equal(t, []string{
+		"http info",
+		"api debug",
+		"users debug",
+		"health error",
+		"testpackage api debug",
+	}, h.Messages())
+}
+
+// TestGroupExactFilter tests that exact group filters take precedence over prefixes.
+func TestGroupExactFilter(t *testing.T) {
+	os.Setenv("GO_LOG", "warn,group:http/*=debug,group:http/api=error")
+	defer os.Unsetenv("GO_LOG")
+
+	h := slogenvtest.NewCaptureHandler()
+	logger := slog.New(slogenv.NewHandler(h))
+
+	api := logger.WithGroup("http").WithGroup("api")
+	api.Info("api info")
+	api.Error("api error")
+	api.WithGroup("users").Info("users info")
+
+	assert.Equal(t, []string{"api error", "users info"}, h.Messages())
+}
+
+// TestGroupNameFilter tests filtering subsystems by the name of their logger's group rather than their package.
+func TestGroupNameFilter(t *testing.T) {
+	t.Setenv("GO_LOG", "info,group:auth=debug,testpackage=error")
+
+	h := slogenvtest.NewCaptureHandler()
+	logger := slog.New(slogenv.NewHandler(h))
+
+	auth := logger.WithGroup("auth")
+	assert.True(t, auth.Enabled(context.Background(), slog.LevelDebug))
+	auth.Debug("auth debug")
+	auth.With("user", "alice").Debug("auth debug with attrs")
+	testpackage.LogSomething(auth, slog.LevelDebug, "testpackage auth debug")
+
+	// An exact group filter doesn't apply to nested groups or to packages with the same name.
+	auth.WithGroup("session").Debug("session debug")
+	logger.Debug("root debug")
+	testpackage.LogSomething(logger, slog.LevelInfo, "testpackage info")
+
+	assert.Equal(t, []string{"auth debug", "auth debug with attrs", "testpackage auth debug"}, h.Messages())
+}
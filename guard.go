@@ -0,0 +1,77 @@
+package slogenv
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// WithAllowRuntimeVerbosityIncrease sets whether runtime updates may make any level more verbose than the
+// levels the handler was created with. Runtime updates are those made by the setters such as SetPackageLevel,
+// UpdateLevels and reloads by WithReloadOnSignal or WithFilterFile. With false, updates may only make the
+// default level or a filter quieter than at startup, and any other update is rejected as a whole with an error,
+// guarding production against an accidental change which floods the logs. It is true by default.
+func WithAllowRuntimeVerbosityIncrease(allowed bool) Opt {
+	return func(cfg *config) {
+		cfg.denyVerbosityIncrease = !allowed
+	}
+}
+
+// HandlerConfig is the configuration a runtime update would give a handler, as passed to the validator
+// set with WithReloadValidation.
+type HandlerConfig struct {
+	// DefaultLevel is the level for packages without a filter.
+	DefaultLevel slog.Level
+	// PackageLevels is the level for each package, group, function, file and attribute filter, keyed like
+	// in filters. Relative levels are resolved against DefaultLevel.
+	PackageLevels map[string]slog.Level
+}
+
+// WithReloadValidation calls validate with the configuration every runtime update would result in, before it
+// is applied, so policies such as "no debug logs from package X in production" can be enforced. Runtime updates
+// are the same as for WithAllowRuntimeVerbosityIncrease. If validate returns an error, the update isn't applied
+// and the error is returned to the caller, such as SetPackageLevel, or reported like an invalid filter for
+// reloads. validate is called while updates are locked, so it must not change the handler's levels itself.
+func WithReloadValidation(validate func(config HandlerConfig) error) Opt {
+	return func(cfg *config) {
+		cfg.reloadValidation = validate
+	}
+}
+
+// handlerConfig returns the configuration described by state.
+func handlerConfig(state *levelState) HandlerConfig {
+	levels := make(map[string]slog.Level, len(state.perPackageLevel))
+	for pkg, level := range state.perPackageLevel {
+		levels[pkg] = level
+	}
+	return HandlerConfig{DefaultLevel: state.defaultLevel, PackageLevels: levels}
+}
+
+// checkVerbosity returns an error for each level in next which is more verbose than the same level in baseline,
+// including filters removed from next whose packages would fall back to a more verbose default level.
+func checkVerbosity(baseline, next *levelState) error {
+	var errs []error
+	if next.defaultLevel < baseline.defaultLevel {
+		errs = append(errs, fmt.Errorf("default level %s is more verbose than %s at startup",
+			formatLevel(next.defaultLevel), formatLevel(baseline.defaultLevel)))
+	}
+
+	for _, key := range sortedPackages(next.perPackageLevel) {
+		start, ok := baseline.perPackageLevel[key]
+		if !ok {
+			start = baseline.defaultLevel
+		}
+		if level := next.perPackageLevel[key]; level < start {
+			errs = append(errs, fmt.Errorf("%s is more verbose than %s at startup",
+				formatRule(key, "", level), formatLevel(start)))
+		}
+	}
+
+	for _, key := range sortedPackages(baseline.perPackageLevel) {
+		if _, ok := next.perPackageLevel[key]; !ok && next.defaultLevel < baseline.perPackageLevel[key] {
+			errs = append(errs, fmt.Errorf("removing %s falls back to the more verbose default level %s",
+				formatRule(key, "", baseline.perPackageLevel[key]), formatLevel(next.defaultLevel)))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,134 @@
+package slogenv_test
+
+import (
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestAllowRuntimeVerbosityIncrease tests that runtime updates may only make levels quieter than at startup
+// when verbosity increases aren't allowed.
+func TestAllowRuntimeVerbosityIncrease(t *testing.T) {
+	t.Setenv("GO_LOG", "info,db=warn")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(), slogenv.WithAllowRuntimeVerbosityIncrease(false))
+
+	assert.EqualError(t, handler.SetDefaultLevel(slog.LevelDebug), "default level DEBUG is more verbose than INFO at startup")
+	assert.EqualError(t, handler.SetPackageLevel("db", slog.LevelInfo), "db=INFO is more verbose than WARN at startup")
+	assert.EqualError(t, handler.SetPackageLevel("cache", slog.LevelDebug), "cache=DEBUG is more verbose than INFO at startup")
+	assert.EqualError(t, handler.RemovePackageLevel("db"), "removing db=WARN falls back to the more verbose default level INFO")
+	assert.Equal(t, slog.LevelInfo, handler.DefaultLevel())
+	assert.Equal(t, map[string]slog.Level{"db": slog.LevelWarn}, handler.PackageLevels())
+
+	// Quieting updates are allowed, and can be undone back to the startup levels.
+	require.NoError(t, handler.SetDefaultLevel(slog.LevelError))
+	require.NoError(t, handler.SetPackageLevel("cache", slog.LevelWarn))
+	require.NoError(t, handler.SetPackageLevel("db", slogenv.LevelOff))
+	assert.Equal(t, map[string]slog.Level{"cache": slog.LevelWarn, "db": slogenv.LevelOff}, handler.PackageLevels())
+	require.NoError(t, handler.SetDefaultLevel(slog.LevelInfo))
+	require.NoError(t, handler.SetPackageLevel("db", slog.LevelWarn))
+
+	// An update is rejected as a whole if any part of it isn't allowed.
+	errorLevel, debug := slog.LevelError, slog.LevelDebug
+	assert.Error(t, handler.UpdateLevels(&errorLevel, map[string]*slog.Level{"api": &debug}))
+	assert.Equal(t, slog.LevelInfo, handler.DefaultLevel())
+	assert.NotContains(t, handler.PackageLevels(), "api")
+}
+
+// TestAllowRuntimeVerbosityIncreaseDefault tests that verbosity increases are allowed by default.
+func TestAllowRuntimeVerbosityIncreaseDefault(t *testing.T) {
+	t.Setenv("GO_LOG", "info,db=warn")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler())
+	require.NoError(t, handler.SetDefaultLevel(slog.LevelDebug))
+	require.NoError(t, handler.RemovePackageLevel("db"))
+}
+
+// TestAllowRuntimeVerbosityIncreaseFilterFile tests that a reloaded filter file which would increase
+// verbosity is rejected, keeping the previous levels.
+func TestAllowRuntimeVerbosityIncreaseFilterFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter")
+	replaceFile(t, path, "warn")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(),
+		slogenv.WithFilterFile(path),
+		slogenv.WithFilterFileInterval(time.Millisecond),
+		slogenv.WithAllowRuntimeVerbosityIncrease(false),
+	)
+	defer handler.Close()
+
+	replaceFile(t, path, "debug")
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, slog.LevelWarn, handler.DefaultLevel())
+
+	replaceFile(t, path, "error")
+	require.Eventually(t, func() bool {
+		return handler.DefaultLevel() == slog.LevelError
+	}, time.Second, time.Millisecond)
+}
+
+// TestReloadValidation tests that runtime updates are only applied when the validator accepts them.
+func TestReloadValidation(t *testing.T) {
+	t.Setenv("GO_LOG", "info,db=warn")
+
+	var validated []slogenv.HandlerConfig
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(), slogenv.WithReloadValidation(func(config slogenv.HandlerConfig) error {
+		validated = append(validated, config)
+		level, ok := config.PackageLevels["payments"]
+		if !ok {
+			level = config.DefaultLevel
+		}
+		if level < slog.LevelInfo {
+			return errors.New("payments must not log below info")
+		}
+		return nil
+	}))
+	assert.Empty(t, validated, "startup levels aren't validated")
+
+	require.NoError(t, handler.SetPackageLevel("payments", slog.LevelWarn))
+	assert.Equal(t, []slogenv.HandlerConfig{{
+		DefaultLevel:  slog.LevelInfo,
+		PackageLevels: map[string]slog.Level{"db": slog.LevelWarn, "payments": slog.LevelWarn},
+	}}, validated)
+
+	assert.EqualError(t, handler.SetPackageLevel("payments", slog.LevelDebug), "payments must not log below info")
+	debug := slog.LevelDebug
+	assert.EqualError(t, handler.UpdateLevels(&debug, map[string]*slog.Level{"payments": nil}), "payments must not log below info")
+	assert.Equal(t, map[string]slog.Level{"db": slog.LevelWarn, "payments": slog.LevelWarn}, handler.PackageLevels())
+}
+
+// TestReloadValidationFilterFile tests that a reloaded filter file rejected by the validator keeps the previous
+// levels.
+func TestReloadValidationFilterFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter")
+	replaceFile(t, path, "warn")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(),
+		slogenv.WithFilterFile(path),
+		slogenv.WithFilterFileInterval(time.Millisecond),
+		slogenv.WithReloadValidation(func(config slogenv.HandlerConfig) error {
+			if config.DefaultLevel < slog.LevelInfo {
+				return errors.New("default level must be at least info")
+			}
+			return nil
+		}),
+	)
+	defer handler.Close()
+
+	replaceFile(t, path, "debug")
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, slog.LevelWarn, handler.DefaultLevel())
+
+	replaceFile(t, path, "info")
+	require.Eventually(t, func() bool {
+		return handler.DefaultLevel() == slog.LevelInfo
+	}, time.Second, time.Millisecond)
+}
@@ -5,6 +5,7 @@
 //   - GO_LOG=info will set the log level to info globally.
 //   - GO_LOG=info,mypackage=debug will set the log level to info by default, but sets it to debug for logs from mypackage.
 //   - GO_LOG=info,mypackage=debug,otherpackage=error you can specify multiple packages by using a comma separator.
+//   - GO_LOG=mypackage=debug sets the level for mypackage, other packages keep the default level (info unless set via WithDefaultLevel).
 //
 // To set up slog-env, wrap your normal slog handler:
 //
@@ -20,16 +21,127 @@ package slogenv
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type config struct {
 	defaultLevel  slog.Level
-	envVarName    string
+	envVarNames   []string
 	defaultFilter string
+	// withoutEnv ignores every environment variable.
+	withoutEnv bool
+	// compactEnvVar is the environment variable the filter is read from in compact form, if set.
+	compactEnvVar string
+	// overrideEnvVar is the environment variable whose filter takes precedence over every other source, if set.
+	overrideEnvVar string
+	// knownPackages are the packages filters may refer to, if set.
+	knownPackages []string
+	// packageLevels are the levels set in code, which the filter is applied on top of.
+	packageLevels map[string]slog.Level
+	// testConvenience makes filters for a package also apply to its external test package.
+	testConvenience bool
+	// decisionLogPath is the file decisions are written to, if set.
+	decisionLogPath string
+	// messagePrefixLevel stores the message prefix filters for each package.
+	messagePrefixLevel map[string][]messagePrefixFilter
+	// unresolvableLevel is the level for records whose package can't be determined, if set.
+	unresolvableLevel *slog.Level
+	// skipStdlibFrames attributes records logged from the standard library to the first user package on the stack.
+	skipStdlibFrames bool
+	// verboseBaseline is the level below which package filters are warned about at startup, if set.
+	verboseBaseline *slog.Level
+	// logConfig logs the resolved levels through the inner handler at construction.
+	logConfig bool
+	// fsFilterLoader loads the default filter from a file system, if set.
+	fsFilterLoader func() (string, error)
+	// annotationFilterLoader loads the default filter from an annotation file, taking precedence over
+	// fsFilterLoader, if set.
+	annotationFilterLoader func() (string, error)
+	// levelTranslation maps level strings from other schemes onto slog levels, if set.
+	levelTranslation func(string) (slog.Level, bool)
+	// levelNames maps lowercased custom level names onto slog levels.
+	levelNames map[string]slog.Level
+	// levelAliases maps lowercased alternative level names onto the level names they stand for.
+	levelAliases map[string]string
+	// spanFromContext returns the span to record matched rules on, if set.
+	spanFromContext func(context.Context) SpanAttributeSetter
+	// tier is the deployment tier used to select tier-guarded filter segments.
+	tier string
+	// expvarName is the name stats are published under in expvar, if set.
+	expvarName string
+	// levelRemap rewrites the levels of emitted records for each package.
+	levelRemap map[string]map[slog.Level]slog.Level
+	// serializeInner serializes calls to the inner handler's Handle.
+	serializeInner bool
+	// maxAttrs limits the number of attributes on records from each package.
+	maxAttrs map[string]int
+	// maxAttrsPolicy decides what happens to records exceeding maxAttrs.
+	maxAttrsPolicy AttrLimitPolicy
+	// sampling keeps one in n records from each package.
+	sampling map[string]int
+	// rateLimits limits the records per second from each package.
+	rateLimits map[string]int
+	// reversedSyntax allows package filters to be written as level@package.
+	reversedSyntax bool
+	// separator separates filter segments in place of commas and semicolons, if set.
+	separator string
+	// inheritDefaultFromPackages uses the most verbose package level as the default if the filter has none.
+	inheritDefaultFromPackages bool
+	// recoverInner recovers panics from the inner handler.
+	recoverInner bool
+	// errorHandler is called with errors returned by the inner handler, if set.
+	errorHandler func(error)
+	// asyncBufferSize is the size of the async queue, or zero to handle records synchronously.
+	asyncBufferSize int
+	// reloadSignal is the signal which reloads the filter, if set.
+	reloadSignal os.Signal
+	// baseContext stops the background goroutines when it's done.
+	baseContext context.Context
+	// fullPackagePath identifies packages by their full import path instead of their name.
+	fullPackagePath bool
+	// packageResolver resolves the package of a caller's frame in place of parsePackage, if set.
+	packageResolver func(runtime.Frame) (string, bool)
+	// attrFilterKeys are the attribute keys checked by attribute filters.
+	attrFilterKeys []string
+	// hierarchicalPackages applies package filters to subpackages too.
+	hierarchicalPackages bool
+	// reloadValidation vetoes runtime updates, if set.
+	reloadValidation func(HandlerConfig) error
+	// denyVerbosityIncrease rejects runtime updates which make any level more verbose than at startup.
+	denyVerbosityIncrease bool
+	// modules are the module paths whose filters match every package in the module, see config.isModuleKey.
+	modules map[string]bool
+	// mergeEnv applies the environment variable on top of the default filter.
+	mergeEnv bool
+	// defaultLeveler holds the default level, replacing defaultLevel, if set.
+	defaultLeveler slog.Leveler
+	// unmatchedOut is where filters which never matched are reported, if set.
+	unmatchedOut io.Writer
+	// warnAmbiguousShortNames warns about package filters matching seen packages with different import paths.
+	warnAmbiguousShortNames bool
+	// suppressionSummary is the interval between summaries of dropped records, if enabled.
+	suppressionSummary *time.Duration
+	// resolveTraceOut is where the resolution of each record is explained, if set.
+	resolveTraceOut io.Writer
+	// filterFile is the file the filter is read from and watched for changes, if set.
+	filterFile string
+	// filterFileInterval is how often filterFile is checked for changes.
+	filterFileInterval time.Duration
+}
+
+// messagePrefixFilter sets the level for records from a package whose message starts with prefix.
+type messagePrefixFilter struct {
+	prefix string
+	level  slog.Level
 }
 
 // Opt allows customizing the handler's configuration.
@@ -42,10 +154,57 @@ func WithDefaultLevel(level slog.Level) Opt {
 	}
 }
 
+// WithDefaultLevelVar uses v for the default level, so it can be shared and changed by other code while
+// the handler is in use. It takes precedence over WithDefaultLevel. If the filter sets a default level,
+// it is stored in v, as are changes made by SetDefaultLevel. It is the same as WithDefaultLeveler(v).
+func WithDefaultLevelVar(v *slog.LevelVar) Opt {
+	return WithDefaultLeveler(v)
+}
+
+// WithDefaultLeveler uses l for the default level, taking precedence over WithDefaultLevel. Its level is read
+// whenever a record is resolved, so a level which changes, such as a *slog.LevelVar, is followed while the
+// handler is in use. If l has a Set(slog.Level) method, like *slog.LevelVar, a default level set by the filter
+// or SetDefaultLevel is stored in it. Otherwise, a default level in the filter, such as from GO_LOG, takes
+// precedence over l when the handler is created, as does SetDefaultLevel, until the level of l changes.
+func WithDefaultLeveler(l slog.Leveler) Opt {
+	return func(cfg *config) {
+		cfg.defaultLeveler = l
+	}
+}
+
 // WithEnvVarName sets the environment variable used to set the log level. Default is GO_LOG.
 func WithEnvVarName(name string) Opt {
 	return func(cfg *config) {
-		cfg.envVarName = name
+		cfg.envVarNames = []string{name}
+	}
+}
+
+// WithEnvVarNames sets several environment variables used to set the log level, such as while migrating
+// from one name to another. The first one listed which is set and non-empty is used.
+// Like WithEnvVarName, it replaces any names set by earlier options.
+func WithEnvVarNames(names ...string) Opt {
+	return func(cfg *config) {
+		cfg.envVarNames = names
+	}
+}
+
+// WithoutEnv stops the handler from reading any environment variable, including those set by WithEnvVarName,
+// WithOverrideEnvVar and WithCompactFilterEnvVar, so the levels only come from options such as WithDefaultFilter,
+// WithDefaultLevel and WithPackageLevels. This is meant for handlers embedded in libraries, which shouldn't
+// change behavior because of a GO_LOG set for the application.
+func WithoutEnv() Opt {
+	return func(cfg *config) {
+		cfg.withoutEnv = true
+	}
+}
+
+// WithOverrideEnvVar sets an environment variable whose filter, when set and non-empty, replaces every other
+// source of the filter, including GO_LOG, the default filter and WithFilterFile, such as SLOG_FORCE=error
+// to quiet everything in CI regardless of a stale GO_LOG. While it is set, changes to WithFilterFile are
+// ignored, but levels can still be changed with methods such as SetPackageLevel.
+func WithOverrideEnvVar(name string) Opt {
+	return func(cfg *config) {
+		cfg.overrideEnvVar = name
 	}
 }
 
@@ -56,140 +215,1062 @@ func WithDefaultFilter(filter string) Opt {
 	}
 }
 
+// WithPackageLevels sets the level for each package, as if they were in the default filter, for configuring
+// levels in code without building a filter string. Keys have the same syntax as packages in filters, so they
+// can also be wildcards or prefixed with group:, and the default level is set with WithDefaultLevel.
+// The environment variable replaces them when set, unless WithMergeEnv is enabled, in which case its segments
+// take precedence over them. Segments of the default filter also take precedence over them.
+// Calling it again adds to the levels.
+func WithPackageLevels(levels map[string]slog.Level) Opt {
+	return func(cfg *config) {
+		if cfg.packageLevels == nil {
+			cfg.packageLevels = make(map[string]slog.Level, len(levels))
+		}
+		for pkg, level := range levels {
+			cfg.packageLevels[pkg] = level
+		}
+	}
+}
+
+// WithMergeEnv applies the environment variable on top of the default filter, instead of replacing it.
+// The default filter, or the filter read by WithFilterAnnotationFile or WithFilterFS, is parsed first and
+// the environment variable's segments are applied after it, so they win where both set the same level.
+// For example, with WithDefaultFilter("info,grpc=warn") and GO_LOG=db=debug, grpc stays at warn.
+func WithMergeEnv(enabled bool) Opt {
+	return func(cfg *config) {
+		cfg.mergeEnv = enabled
+	}
+}
+
+// WithTestConvenience makes a filter for a package also match its external test package.
+// With this enabled, GO_LOG=mypkg=debug also covers logs from mypkg_test, unless mypkg_test has its own filter.
+func WithTestConvenience(enabled bool) Opt {
+	return func(cfg *config) {
+		cfg.testConvenience = enabled
+	}
+}
+
+// WithDecisionLog writes a JSON line describing every filtering decision to the file at path.
+// The file is rotated once it grows past 10 MiB, keeping a single previous file with a .1 suffix.
+// If the file can't be opened, decisions are not logged and NewHandlerWithError reports the problem.
+// Call Close to flush and close the file.
+func WithDecisionLog(path string) Opt {
+	return func(cfg *config) {
+		cfg.decisionLogPath = path
+	}
+}
+
+// WithMessagePrefixFilter sets the log level for records from pkg whose message starts with prefix.
+// This takes precedence over the package's filter, allowing a subset of a package's logs to be made more
+// or less verbose. If several prefixes match a message, the filter added last wins.
+func WithMessagePrefixFilter(pkg, prefix string, level slog.Level) Opt {
+	return func(cfg *config) {
+		if cfg.messagePrefixLevel == nil {
+			cfg.messagePrefixLevel = make(map[string][]messagePrefixFilter)
+		}
+		cfg.messagePrefixLevel[pkg] = append(cfg.messagePrefixLevel[pkg], messagePrefixFilter{prefix: prefix, level: level})
+	}
+}
+
+// WithUnresolvableLevel sets the log level for records whose package can't be determined from the caller,
+// such as records created without a PC. By default these use the default level.
+func WithUnresolvableLevel(level slog.Level) Opt {
+	return func(cfg *config) {
+		cfg.unresolvableLevel = &level
+	}
+}
+
+// WithSkipStdlibFrames attributes records logged from within the standard library, such as through
+// a [log.Logger] bridged to slog, to the first non-standard-library package further up the stack.
+// A package is assumed to be in the standard library if the first element of its import path has no dot,
+// so modules with dotless paths other than main are also skipped.
+//
+// This walks the stack in Handle, so it only works when records are handled synchronously.
+func WithSkipStdlibFrames(enabled bool) Opt {
+	return func(cfg *config) {
+		cfg.skipStdlibFrames = enabled
+	}
+}
+
+// WithWarnVerboseOverrides logs a warning through the inner handler at construction for every package
+// whose level is more verbose than baseline. This helps catch settings like db=debug left in a production config.
+func WithWarnVerboseOverrides(baseline slog.Level) Opt {
+	return func(cfg *config) {
+		cfg.verboseBaseline = &baseline
+	}
+}
+
+// WithLogConfig logs the resolved default and package levels through the inner handler at construction,
+// as a single info record. This makes it easy to see how the filter was interpreted.
+func WithLogConfig(enabled bool) Opt {
+	return func(cfg *config) {
+		cfg.logConfig = enabled
+	}
+}
+
+// WithLevelRemap rewrites the level of records from pkg logged at from to to before passing them to the inner handler.
+// This is useful for packages which log at inappropriate levels, such as reporting errors which are really warnings.
+// Filtering is still decided by the original level.
+func WithLevelRemap(pkg string, from, to slog.Level) Opt {
+	return func(cfg *config) {
+		if cfg.levelRemap == nil {
+			cfg.levelRemap = make(map[string]map[slog.Level]slog.Level)
+		}
+		if cfg.levelRemap[pkg] == nil {
+			cfg.levelRemap[pkg] = make(map[slog.Level]slog.Level)
+		}
+		cfg.levelRemap[pkg][from] = to
+	}
+}
+
+// WithSerializeInner serializes calls to the inner handler's Handle with a mutex shared by all handlers
+// derived from this one. Use this to protect inner handlers which aren't safe for concurrent use.
+// This limits logging throughput to a single goroutine at a time, so only enable it when needed.
+func WithSerializeInner(enabled bool) Opt {
+	return func(cfg *config) {
+		cfg.serializeInner = enabled
+	}
+}
+
+// WithRecoverInner recovers panics from the inner handler's Handle, so a faulty handler can't crash the application.
+// A recovered panic is reported with a one-line notice on stderr and returned from Handle as an error.
+func WithRecoverInner(enabled bool) Opt {
+	return func(cfg *config) {
+		cfg.recoverInner = enabled
+	}
+}
+
+// WithErrorHandler sets a function which is called with every error returned by the inner handler's Handle,
+// such as a write failing because the disk is full, so failures can be counted or reported elsewhere.
+// The error is still returned from Handle. It is also called for panics recovered with WithRecoverInner,
+// and for records handled in the background with WithAsync, whose errors are otherwise discarded.
+// It may be called concurrently.
+func WithErrorHandler(handle func(error)) Opt {
+	return func(cfg *config) {
+		cfg.errorHandler = handle
+	}
+}
+
+// WithFullPackagePath identifies packages by their full import path instead of the last element of it,
+// so filters like GO_LOG=github.com/acme/config=debug don't also match other packages named config.
+// Package names in filters, and in options such as WithMessagePrefixFilter, then no longer match.
+func WithFullPackagePath(enabled bool) Opt {
+	return func(cfg *config) {
+		cfg.fullPackagePath = enabled
+	}
+}
+
+// WithPackageResolver sets a function which determines the package of the frame a record was logged from,
+// in place of the package name parsed from the frame's function name, for setups where function names don't
+// follow the usual form, such as obfuscated binaries. It isn't called for records without a caller. If it returns false, the package is treated as unresolvable,
+// see WithUnresolvableLevel. Resolved packages are cached by program counter, so it should be deterministic.
+// Matching full import paths and wildcards still uses the import path from the function name.
+func WithPackageResolver(resolve func(frame runtime.Frame) (pkg string, ok bool)) Opt {
+	return func(cfg *config) {
+		cfg.packageResolver = resolve
+	}
+}
+
+// WithHierarchicalPackages applies package filters to subpackages as well, so GO_LOG=acme/api=debug also
+// sets the level for acme/api/users and acme/api/orders. When several filters match a package, the one matching
+// the most of its import path wins, so subpackages can still have their own filters.
+func WithHierarchicalPackages(enabled bool) Opt {
+	return func(cfg *config) {
+		cfg.hierarchicalPackages = enabled
+	}
+}
+
 // Handler is a log handler that dynamically sets the log level based on the GO_LOG environment variable.
 // The log level can be set on a per-package basis.
 type Handler struct {
-	inner slog.Handler
-	// defaultLevel is the log level used for logs not matching one of the package filters.
-	defaultLevel slog.Level
-	// perPackageLevel stores the log level for each package.
-	perPackageLevel map[string]slog.Level
+	// root holds the inner handler passed to NewHandler or SwapInner, shared by all derived handlers.
+	root *atomic.Pointer[innerVersion]
+	// inner caches this handler's inner handler, derived from the current root.
+	inner *atomic.Pointer[derivedInner]
+	// derivations are the WithAttrs and WithGroup calls applied to the root to derive this handler.
+	derivations []func(slog.Handler) slog.Handler
+	// cfg is the configuration the handler was created with, used to reload the filter.
+	cfg *config
+	// state holds the levels configured by the filter, shared by all derived handlers.
+	state *atomic.Pointer[levelState]
+	// stateMu serializes updates to state.
+	stateMu *sync.Mutex
+	// subscribers receive runtime changes to state, shared by all derived handlers.
+	subscribers *subscribers
+	// testConvenience makes filters for a package also apply to its external test package.
+	testConvenience bool
+	// messagePrefixLevel stores the message prefix filters for each package.
+	messagePrefixLevel map[string][]messagePrefixFilter
+	// groups is the group path this handler was derived with via WithGroup.
+	groups []string
+	// group caches the group filter matching groups for the current level state.
+	group *atomic.Pointer[groupMatch]
+	// attrs are the attributes this handler was derived with via WithAttrs which attribute filters check.
+	attrs []slog.Attr
+	// skipStdlibFrames attributes records logged from the standard library to the first user package on the stack.
+	skipStdlibFrames bool
+	// fullPackagePath identifies packages by their full import path instead of their name.
+	fullPackagePath bool
+	// packageResolver resolves the package of a caller's frame in place of parsePackage, if set.
+	packageResolver func(runtime.Frame) (string, bool)
+	// packages caches the package resolved for each program counter, shared by all derived handlers, if set.
+	packages *sync.Map
+	// spanFromContext returns the span to record matched rules on, if set.
+	spanFromContext func(context.Context) SpanAttributeSetter
+	// levelRemap rewrites the levels of emitted records for each package.
+	levelRemap map[string]map[slog.Level]slog.Level
+	// maxAttrs limits the number of attributes on records from each package while it is at debug.
+	maxAttrs map[string]int
+	// maxAttrsPolicy decides what happens to records exceeding maxAttrs.
+	maxAttrsPolicy AttrLimitPolicy
+	// samplers decide which records to keep from each sampled package, shared by all derived handlers.
+	samplers map[string]*sampler
+	// rateLimiters limit the records from each rate limited package, shared by all derived handlers.
+	rateLimiters map[string]*rateLimiter
+	// innerMu serializes calls to the inner handler, if enabled.
+	innerMu *sync.Mutex
+	// recoverInner recovers panics from the inner handler.
+	recoverInner bool
+	// errorHandler is called with errors returned by the inner handler, if set.
+	errorHandler func(error)
+	// async passes records to the inner handler from a background goroutine, if enabled.
+	async *asyncQueue
+	// reloader reloads the filter when the process receives a signal, if enabled.
+	reloader *reloader
+	// watcher reloads the filter when the filter file changes, if enabled.
+	watcher *fileWatcher
+	// stats counts emitted and dropped records, if enabled.
+	stats *stats
+	// baseline is the level state the handler was created with, which runtime updates may not make more
+	// verbose, if set.
+	baseline *levelState
+	// decisions records filtering decisions, if enabled.
+	decisions *decisionLog
+	// unmatched tracks filters which haven't matched a record, if enabled.
+	unmatched *unmatchedFilters
+	// ambiguous warns about package filters matching seen packages with different import paths, if enabled.
+	ambiguous *ambiguousNames
+	// tracer explains the resolution of each record, if enabled.
+	tracer *resolveTracer
+	// suppression counts dropped records for summaries, if enabled.
+	suppression *suppressionSummary
 }
 
 var _ slog.Handler = (*Handler)(nil)
 
-// NewHandler creates a new env logger handler.
-func NewHandler(inner slog.Handler, opts ...Opt) *Handler {
+// newConfig creates a config with the defaults and applies opts to it.
+func newConfig(opts ...Opt) config {
 	cfg := config{
-		envVarName:   "GO_LOG",
-		defaultLevel: slog.LevelInfo,
+		envVarNames:        []string{"GO_LOG"},
+		defaultLevel:       slog.LevelInfo,
+		filterFileInterval: defaultFilterFileInterval,
+		modules:            buildModules(),
+		baseContext:        context.Background(),
 	}
 
 	for _, opt := range opts {
 		opt(&cfg)
 	}
 
+	return cfg
+}
+
+// NewHandler creates a new env logger handler.
+// Invalid parts of the filter are ignored, use NewHandlerWithError to detect them.
+func NewHandler(inner slog.Handler, opts ...Opt) *Handler {
+	h, _ := NewHandlerWithError(inner, opts...)
+	return h
+}
+
+// NewHandlerWithError creates a new env logger handler, reporting any problems with its configuration,
+// such as segments of the filter with an invalid level or filter files which couldn't be read.
+// The handler is always returned, configured the same way NewHandler would, so callers can choose
+// to report the error and carry on.
+func NewHandlerWithError(inner slog.Handler, opts ...Opt) (*Handler, error) {
+	cfg := newConfig(opts...)
+
+	var errs []error
+	state, err := cfg.loadLevels()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.checkKnownPackages(state.perPackageLevel); err != nil {
+		errs = append(errs, err)
+	}
+
+	h := &Handler{
+		cfg:                &cfg,
+		state:              &atomic.Pointer[levelState]{},
+		stateMu:            &sync.Mutex{},
+		subscribers:        &subscribers{},
+		group:              &atomic.Pointer[groupMatch]{},
+		testConvenience:    cfg.testConvenience,
+		messagePrefixLevel: cfg.messagePrefixLevel,
+		skipStdlibFrames:   cfg.skipStdlibFrames,
+		fullPackagePath:    cfg.fullPackagePath,
+		packageResolver:    cfg.packageResolver,
+		packages:           &sync.Map{},
+		spanFromContext:    cfg.spanFromContext,
+		levelRemap:         cfg.levelRemap,
+		maxAttrs:           cfg.maxAttrs,
+		maxAttrsPolicy:     cfg.maxAttrsPolicy,
+		samplers:           newSamplers(cfg.sampling),
+		rateLimiters:       newRateLimiters(cfg.rateLimits),
+		recoverInner:       cfg.recoverInner,
+		errorHandler:       cfg.errorHandler,
+		root:               &atomic.Pointer[innerVersion]{},
+		inner:              &atomic.Pointer[derivedInner]{},
+	}
+	h.storeLevels(state)
+	if cfg.denyVerbosityIncrease {
+		h.baseline = state
+	}
+	version := &innerVersion{handler: inner}
+	h.root.Store(version)
+	h.inner.Store(&derivedInner{version: version, handler: inner})
+
+	if cfg.asyncBufferSize > 0 {
+		h.async = newAsyncQueue(cfg.asyncBufferSize)
+	}
+
+	if cfg.serializeInner {
+		h.innerMu = &sync.Mutex{}
+	}
+
+	if cfg.verboseBaseline != nil {
+		h.warnVerboseOverrides(*cfg.verboseBaseline)
+	}
+	if cfg.logConfig {
+		h.logConfig()
+	}
+
+	if cfg.decisionLogPath != "" {
+		if h.decisions, err = openDecisionLog(cfg.decisionLogPath, defaultDecisionLogMaxBytes); err != nil {
+			errs = append(errs, fmt.Errorf("opening decision log: %w", err))
+		}
+	}
+
+	if cfg.expvarName != "" {
+		h.stats = &stats{}
+		h.publishExpvar(cfg.expvarName)
+	}
+
+	if cfg.reloadSignal != nil {
+		h.reloader = h.reloadOnSignal(cfg.reloadSignal)
+	}
+
+	if cfg.suppressionSummary != nil {
+		h.suppression = newSuppressionSummary(time.Now)
+		if interval := *cfg.suppressionSummary; interval > 0 {
+			h.suppression.summarizeEvery(h, interval)
+		}
+	}
+	if cfg.resolveTraceOut != nil {
+		h.tracer = &resolveTracer{out: cfg.resolveTraceOut}
+	}
+	if cfg.unmatchedOut != nil {
+		h.unmatched = &unmatchedFilters{out: cfg.unmatchedOut}
+	}
+	if cfg.warnAmbiguousShortNames {
+		h.ambiguous = &ambiguousNames{out: os.Stderr}
+	}
+
+	if cfg.filterFile != "" {
+		interval := cfg.filterFileInterval
+		if interval <= 0 {
+			errs = append(errs, fmt.Errorf("filter file interval %v isn't positive, using %v",
+				interval, defaultFilterFileInterval))
+			interval = defaultFilterFileInterval
+		}
+		h.watcher = h.watchFilterFile(cfg.filterFile, interval)
+	}
+
+	return h, errors.Join(errs...)
+}
+
+// loadLevels loads the filter from the filter file or the environment variable, falling back to the filter loader
+// and the default filter, and parses it into a level state.
+func (cfg *config) loadLevels() (*levelState, error) {
+	if override := cfg.overrideFilter(); override != "" {
+		return cfg.parseLevels(override)
+	}
+
+	var errs []error
+
+	if cfg.filterFile != "" {
+		data, err := os.ReadFile(cfg.filterFile)
+		if err == nil {
+			return cfg.parseLevels(string(data))
+		}
+		errs = append(errs, fmt.Errorf("reading filter file: %w", err))
+	}
+
 	filter := cfg.defaultFilter
-	if envFilter := os.Getenv(cfg.envVarName); envFilter != "" {
-		filter = envFilter
+	for _, load := range []func() (string, error){cfg.fsFilterLoader, cfg.annotationFilterLoader} {
+		if load == nil {
+			continue
+		}
+		loaded, err := load()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("loading filter: %w", err))
+		} else {
+			filter = loaded
+		}
+	}
+	packageLevels := cfg.packageLevels
+	envFilter, err := cfg.envFilter()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if envFilter != "" {
+		if cfg.mergeEnv && filter != "" {
+			// Later segments take precedence, so the environment variable's are applied last.
+			filter = unquote(filter) + cfg.segmentSeparator() + unquote(envFilter)
+		} else {
+			filter = envFilter
+			if !cfg.mergeEnv {
+				packageLevels = nil
+			}
+		}
 	}
 
-	defaultLevel, perPackageLevel := parseFilter(cfg.defaultLevel, filter)
+	state, err := cfg.parseLevelsOver(packageLevels, filter)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return state, errors.Join(errs...)
+}
 
-	return &Handler{
-		defaultLevel:    defaultLevel,
-		perPackageLevel: perPackageLevel,
-		inner:           inner,
+// parseLevels parses filter into a level state.
+func (cfg *config) parseLevels(filter string) (*levelState, error) {
+	return cfg.parseLevelsOver(nil, filter)
+}
+
+// parseLevelsOver parses filter into a level state, keeping the level in packageLevels
+// for packages the filter doesn't set.
+func (cfg *config) parseLevelsOver(packageLevels map[string]slog.Level, filter string) (*levelState, error) {
+	parseCfg := cfg
+	if cfg.defaultLeveler != nil {
+		withVar := *cfg
+		withVar.defaultLevel = cfg.defaultLeveler.Level()
+		parseCfg = &withVar
+	}
+	parsed, err := parseFilter(parseCfg, filter)
+
+	var errs []error
+	for _, pkg := range sortedPackages(packageLevels) {
+		if err := checkPackagePattern(pkg); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, ok := parsed.perPackageLevel[pkg]; !ok {
+			parsed.perPackageLevel[pkg] = packageLevels[pkg]
+		}
+	}
+	if err != nil {
+		errs = append([]error{err}, errs...)
+	}
+	return newLevelState(cfg, parsed), errors.Join(errs...)
+}
+
+// overrideFilter returns the value of the override environment variable, if one is configured and set,
+// and the environment isn't ignored.
+func (cfg *config) overrideFilter() string {
+	if cfg.overrideEnvVar == "" || cfg.withoutEnv {
+		return ""
 	}
+	return os.Getenv(cfg.overrideEnvVar)
 }
 
-// Enabled implements slog.Handler.
+// envFilter returns the value of the first environment variable which is set and non-empty,
+// falling back to the compact environment variable. It returns an empty filter if the environment is ignored.
+func (cfg *config) envFilter() (string, error) {
+	if cfg.withoutEnv {
+		return "", nil
+	}
+	for _, name := range cfg.envVarNames {
+		if filter := os.Getenv(name); filter != "" {
+			return filter, nil
+		}
+	}
+	return cfg.compactEnvFilter()
+}
+
+// SetAsDefault creates a new env logger handler wrapping inner and installs it as the default logger
+// via [slog.SetDefault], so logs from packages using [slog.Default] or the top-level slog functions are filtered.
+// The handler is returned so it can be used for further configuration.
+func SetAsDefault(inner slog.Handler, opts ...Opt) *Handler {
+	h := NewHandler(inner, opts...)
+	slog.SetDefault(slog.New(h))
+	return h
+}
+
+// Validate checks that filter is valid given the options, without creating a handler.
+// This can be used by tools or CI checks to verify a GO_LOG value before deploying it.
+// The returned error names every invalid segment of the filter.
+func Validate(filter string, opts ...Opt) error {
+	cfg := newConfig(opts...)
+	parsed, err := parseFilter(&cfg, filter)
+	return errors.Join(err, cfg.checkKnownPackages(parsed.perPackageLevel))
+}
+
+// EffectiveLevels returns the level configured for each package filter, sorted by package name
+// so the output is stable across runs.
+func (h *Handler) EffectiveLevels() []PackageLevel {
+	state := h.levels()
+	levels := make([]PackageLevel, 0, len(state.perPackageLevel))
+	for _, pkg := range sortedPackages(state.perPackageLevel) {
+		levels = append(levels, PackageLevel{Package: pkg, Level: state.perPackageLevel[pkg]})
+	}
+	return levels
+}
+
+// LevelForPC returns the minimum level for a record logged from the program counter pc to be emitted.
+// This allows levels to be resolved ahead of time for known call sites.
+func (h *Handler) LevelForPC(pc uintptr) slog.Level {
+	state := h.levels()
+	pkg, path, ok := h.resolvePackage(pc)
+	if !ok {
+		return state.unresolvableLevel
+	}
+
+	if level, _, ok := h.packageLevel(state, pkg, path); ok {
+		return level
+	}
+	return state.defaultLevel
+}
+
+// EnabledForPackage reports whether a record at level logged from pkg through this handler would be emitted,
+// without needing a record or program counter. pkg can be a package name or a full import path, which also
+// matches filters for the last element of the path. Group filters for this handler's groups apply as they
+// would to records, but message prefix filters and levels overridden in a context don't.
+func (h *Handler) EnabledForPackage(pkg string, level slog.Level) bool {
+	state := h.levels()
+	if rule, groupLevel := h.groupFilter(state); rule != "" {
+		return level >= groupLevel
+	}
+
+	name := pkg
+	if !h.fullPackagePath {
+		name = pkg[strings.LastIndex(pkg, "/")+1:]
+	}
+
+	threshold, _, ok := h.packageLevel(state, name, pkg)
+	if !ok {
+		threshold = state.defaultLevel
+	}
+	return level >= threshold
+}
+
+// WouldEverEnable reports whether any record from pkg could be emitted, along with the minimum level a record
+// from pkg needs to be emitted. Like in EnabledForPackage, pkg is the package's import path or name.
+// A package is never enabled if its level is [LevelOff]. Message prefix filters, attribute filters and function and file
+// filters which could match code in pkg are taken into account, where a file filter without a directory, such as
+// file:server.go, could match a file in any package.
+func (h *Handler) WouldEverEnable(pkg string) (bool, slog.Level) {
+	state := h.levels()
+	name := pkg
+	if !h.fullPackagePath {
+		name = pkg[strings.LastIndex(pkg, "/")+1:]
+	}
+
+	level, _, ok := h.packageLevel(state, name, pkg)
+	if !ok {
+		level = state.defaultLevel
+	}
+
+	for _, filter := range h.messagePrefixLevel[name] {
+		level = min(level, filter.level)
+	}
+	for _, target := range state.targets {
+		if target.mayMatchPackage(pkg) {
+			level = min(level, target.level)
+		}
+	}
+	if state.attrFilters {
+		// Any record could carry a matching attribute.
+		for key, filterLevel := range state.perPackageLevel {
+			if attr, ok := strings.CutPrefix(key, attrPrefix); ok {
+				if attrKey, _, _ := strings.Cut(attr, "="); h.isAttrFilterKey(attrKey) {
+					level = min(level, filterLevel)
+				}
+			}
+		}
+	}
+
+	return level < LevelOff, level
+}
+
+// Close releases any resources held by the handler, such as the decision log and the goroutines watching for
+// WithReloadOnSignal and WithFilterFile, reports filters which never matched if WithUnmatchedFilterWarning
+// is set and sends the last summary for WithSuppressionSummary, after passing any records queued by WithAsync
+// to the inner handler.
+// Handlers derived via WithAttrs or WithGroup share these resources, so Close only needs to be called once.
+func (h *Handler) Close() error {
+	if h.reloader != nil {
+		h.reloader.stop()
+	}
+	if h.watcher != nil {
+		h.watcher.stop()
+	}
+	if h.unmatched != nil {
+		h.unmatched.report(h.levels())
+	}
+	if h.suppression != nil {
+		h.suppression.stop()
+		h.suppression.summarize(h)
+	}
+	if h.async != nil {
+		h.async.close()
+	}
+	if h.decisions != nil {
+		return h.decisions.close()
+	}
+	return nil
+}
+
+// reportDecision writes the decision whether to emit the record to the decision log and the expvar stats,
+// if they are enabled.
+func (h *Handler) reportDecision(record slog.Record, res resolution, emit bool) {
+	if h.decisions != nil {
+		pkg, _ := h.recordPackage(record, res)
+		h.decisions.write(DecisionTrace{
+			Time:      record.Time,
+			Message:   record.Message,
+			Level:     record.Level,
+			Package:   pkg,
+			Rule:      res.rule(),
+			Threshold: res.level,
+			Emitted:   emit,
+		})
+	}
+
+	if h.stats != nil {
+		if emit {
+			h.stats.emitted.Add(1)
+		} else {
+			h.stats.dropped.Add(1)
+		}
+	}
+}
+
+// Enabled implements slog.Handler. As a fast path, records below the lowest level any filter allows are
+// rejected here, before a record is built or its package resolved. WithDecisionLog, WithUnmatchedFilterWarning,
+// WithResolveTrace and WithSuppressionSummary need to see every record, so Enabled always returns true while
+// any of them is set. WithDecisionLog also resolves the package of every record, and WithSuppressionSummary
+// of every dropped one.
 func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
-	if len(h.perPackageLevel) == 0 {
-		return level >= h.defaultLevel
+	if h.decisions != nil || h.unmatched != nil || h.tracer != nil || h.suppression != nil {
+		return true
 	}
 
-	// Unfortunately, when filtering by package, we need to wait
-	// until Handle is called before we determine if a log is enabled.
-	return true
+	// A level override in the context decides on its own. Otherwise, records below the band are never emitted.
+	// Unfortunately, for records within the band we need to wait until Handle is called before we determine
+	// if a log is enabled.
+	if override, ok := levelOverride(ctx); ok {
+		return level >= override
+	}
+	return level >= h.levels().minLevel
 }
 
 // Handle implements slog.Handler.
 func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
-	level := h.getLevelForRecord(record)
+	var res resolution
+	if h.tracer != nil {
+		var trace ResolutionTrace
+		trace, res = h.trace(ctx, record)
+		h.tracer.write(record, trace, res)
+	} else {
+		res = h.getLevelForRecord(ctx, record)
+	}
+	emit := record.Level >= res.level
+
+	if h.unmatched != nil && res.filterPackage != "" {
+		h.unmatched.match(res.filterPackage)
+	}
 
-	if record.Level < level {
+	if !emit && h.suppression != nil {
+		pkg, _ := h.recordPackage(record, res)
+		h.suppression.count(pkg, record.Level)
+	}
+
+	// Sampling, rate limiting and the attribute limit can still drop the record, so the decision is only
+	// reported once it is final.
+	if emit && len(h.samplers) > 0 {
+		emit = h.sample(record, res)
+	}
+	if emit && len(h.rateLimiters) > 0 {
+		emit = h.rateLimit(record, res)
+	}
+	if emit && len(h.maxAttrs) > 0 {
+		record, emit = h.limitAttrs(record, res)
+	}
+
+	h.reportDecision(record, res, emit)
+	if !emit {
+		return nil
+	}
+
+	if len(h.levelRemap) > 0 {
+		record = h.remapLevel(record, res)
+	}
+
+	if h.spanFromContext != nil && res.matched() {
+		if span := h.spanFromContext(ctx); span != nil {
+			span.SetAttribute(SpanRuleAttributeKey, res.rule())
+		}
+	}
+
+	if h.async != nil && h.async.enqueue(ctx, h, record) {
 		return nil
 	}
 
-	return h.inner.Handle(ctx, record)
+	return h.handleInner(ctx, record)
+}
+
+// handleInner passes the record to the inner handler.
+func (h *Handler) handleInner(ctx context.Context, record slog.Record) (err error) {
+	if h.errorHandler != nil {
+		// Deferred first so it runs last, after a recovered panic has been turned into an error.
+		defer func() {
+			if err != nil {
+				h.errorHandler(err)
+			}
+		}()
+	}
+
+	if h.recoverInner {
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Fprintf(os.Stderr, "slog-env: recovered panic in inner handler: %v\n", r)
+				err = fmt.Errorf("slog-env: inner handler panicked: %v", r)
+			}
+		}()
+	}
+
+	if h.innerMu != nil {
+		h.innerMu.Lock()
+		defer h.innerMu.Unlock()
+	}
+
+	return h.innerHandler().Handle(ctx, record)
 }
 
 // WithAttrs implements slog.Handler.
 func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return &Handler{
-		inner:           h.inner.WithAttrs(attrs),
-		defaultLevel:    h.defaultLevel,
-		perPackageLevel: h.perPackageLevel,
+	derived := h.derive(func(inner slog.Handler) slog.Handler {
+		return inner.WithAttrs(attrs)
+	})
+	if len(h.cfg.attrFilterKeys) > 0 {
+		if tracked := h.trackAttrs(attrs); len(tracked) > 0 {
+			derived.attrs = append(h.attrs[:len(h.attrs):len(h.attrs)], tracked...)
+		}
 	}
+	return derived
 }
 
 // WithGroup implements slog.Handler.
 func (h *Handler) WithGroup(name string) slog.Handler {
-	return &Handler{
-		inner:           h.inner.WithGroup(name),
-		defaultLevel:    h.defaultLevel,
-		perPackageLevel: h.perPackageLevel,
+	// Per the slog.Handler contract, an empty group name leaves the handler unchanged.
+	if name == "" {
+		return h
 	}
+
+	derived := h.derive(func(inner slog.Handler) slog.Handler {
+		return inner.WithGroup(name)
+	})
+	derived.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	derived.group = &atomic.Pointer[groupMatch]{}
+	return derived
 }
 
-func (h *Handler) getLevelForRecord(record slog.Record) slog.Level {
-	if len(h.perPackageLevel) == 0 {
-		return h.defaultLevel
+// warnVerboseOverrides logs a warning directly to the inner handler for each package more verbose than baseline.
+func (h *Handler) warnVerboseOverrides(baseline slog.Level) {
+	ctx := context.Background()
+	inner := h.innerHandler()
+	if !inner.Enabled(ctx, slog.LevelWarn) {
+		return
 	}
 
-	fs := runtime.CallersFrames([]uintptr{record.PC})
-	f, _ := fs.Next()
-	pkg, ok := parsePackage(f.Function)
-	if !ok {
-		return h.defaultLevel
+	state := h.levels()
+	for _, pkg := range sortedPackages(state.perPackageLevel) {
+		level := state.perPackageLevel[pkg]
+		if level >= baseline {
+			continue
+		}
+
+		record := slog.NewRecord(time.Now(), slog.LevelWarn, "slog-env: package log level is more verbose than baseline", 0)
+		record.AddAttrs(
+			slog.String("package", pkg),
+			slog.Any("level", level),
+			slog.Any("baseline", baseline),
+		)
+		_ = inner.Handle(ctx, record)
+	}
+}
+
+// logConfig logs the resolved levels directly to the inner handler, bypassing the filter.
+func (h *Handler) logConfig() {
+	ctx := context.Background()
+	inner := h.innerHandler()
+	if !inner.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+
+	state := h.levels()
+	packages := make([]any, 0, len(state.perPackageLevel))
+	for _, pkg := range sortedPackages(state.perPackageLevel) {
+		packages = append(packages, slog.String(pkg, formatLevel(state.perPackageLevel[pkg])))
+	}
+
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "slog-env: configuration", 0)
+	record.AddAttrs(
+		slog.String("default", formatLevel(state.defaultLevel)),
+		slog.Group("packages", packages...),
+	)
+	_ = inner.Handle(ctx, record)
+}
+
+// resolution is the outcome of resolving the level for a record.
+type resolution struct {
+	// pkg is the package the record was logged from. It is empty if it isn't needed or can't be determined.
+	pkg string
+	// path is the full import path of pkg.
+	path string
+	// level is the minimum level for the record to be emitted.
+	level slog.Level
+	// filterPackage is the package key of the filter which matched, if any.
+	filterPackage string
+	// messagePrefix is the message prefix of the filter which matched, if any.
+	messagePrefix string
+}
+
+// matched reports whether a package filter, rather than a default, decided the level.
+func (r resolution) matched() bool {
+	return r.filterPackage != ""
+}
+
+// rule formats the filter which matched, or returns an empty string if none did.
+func (r resolution) rule() string {
+	if !r.matched() {
+		return ""
 	}
+	return formatRule(r.filterPackage, r.messagePrefix, r.level)
+}
 
-	level, ok := h.perPackageLevel[pkg]
+// formatRule formats a filter for a package, or a package and message prefix, in filter syntax.
+func formatRule(pkg, messagePrefix string, level slog.Level) string {
+	if messagePrefix != "" {
+		return fmt.Sprintf("%s %q=%s", pkg, messagePrefix, formatLevel(level))
+	}
+	return pkg + "=" + formatLevel(level)
+}
+
+// getLevelForRecord resolves the package the record was logged from and the minimum level for it to be emitted.
+// A level override in ctx takes precedence over every filter.
+func (h *Handler) getLevelForRecord(ctx context.Context, record slog.Record) resolution {
+	if level, ok := levelOverride(ctx); ok {
+		return resolution{level: level}
+	}
+
+	state := h.levels()
+
+	// Outside of the band the package can't change the outcome, so skip resolving it,
+	// unless every record is checked for unmatched filters or has its decision logged.
+	if rule, _ := h.groupFilter(state); rule == "" && h.unmatched == nil && h.decisions == nil {
+		if record.Level < state.minLevel {
+			return resolution{level: state.minLevel}
+		}
+		if record.Level >= state.maxLevel {
+			return resolution{level: state.maxLevel}
+		}
+	}
+
+	return h.resolve(state, record, nil)
+}
+
+// resolve resolves the level for a record by checking each kind of filter in order of precedence.
+// If trace is non-nil, every filter considered is recorded in it.
+func (h *Handler) resolve(state *levelState, record slog.Record, trace *ResolutionTrace) resolution {
+	// Group filters take precedence over all other filters.
+	if len(h.groups) > 0 {
+		trace.consider(groupPrefix + strings.Join(h.groups, "/"))
+	}
+	if rule, level := h.groupFilter(state); rule != "" {
+		return resolution{level: level, filterPackage: rule}
+	}
+
+	if state.attrFilters {
+		if rule, level, ok := h.matchAttrFilter(state, record); ok {
+			trace.consider(rule)
+			return resolution{level: level, filterPackage: rule}
+		}
+	}
+
+	caller := h.resolveCaller(record.PC)
+	pkg, path := caller.pkg, caller.path
+	if !caller.ok {
+		trace.consider("unresolvable")
+		return resolution{level: state.unresolvableLevel}
+	}
+
+	// Function and file filters are more specific than package filters, so they take precedence.
+	for _, target := range state.targets {
+		trace.consider(target.key)
+		if target.matches(caller) {
+			return resolution{pkg: pkg, path: path, level: target.level, filterPackage: target.key}
+		}
+	}
+
+	filters := h.messagePrefixLevel[pkg]
+	for i := len(filters) - 1; i >= 0; i-- {
+		if trace != nil {
+			trace.consider(formatRule(pkg, filters[i].prefix, filters[i].level))
+		}
+		if strings.HasPrefix(record.Message, filters[i].prefix) {
+			return resolution{pkg: pkg, path: path, level: filters[i].level, filterPackage: pkg, messagePrefix: filters[i].prefix}
+		}
+	}
+
+	trace.consider(pkg)
+	level, filterPackage, ok := h.packageLevel(state, pkg, path)
 	if !ok {
-		return h.defaultLevel
+		trace.consider("default")
+		return resolution{pkg: pkg, path: path, level: state.defaultLevel}
 	}
 
-	return level
+	return resolution{pkg: pkg, path: path, level: level, filterPackage: filterPackage}
 }
 
-// parsePackage parses the package out of a formatted function name.
-// Example:
-// github.com/cbrewster/slog-env_test.TestFilterPackage
-// Will return slog-env_test
-func parsePackage(function string) (string, bool) {
-	parts := strings.Split(function, "/")
-	pkg, _, ok := strings.Cut(parts[len(parts)-1], ".")
-	return pkg, ok
+// packageLevel returns the level of the package filter in state matching pkg, whose full import path is path,
+// and the key of that filter. Filters for the package name or full path take precedence over wildcard filters.
+func (h *Handler) packageLevel(state *levelState, pkg, path string) (slog.Level, string, bool) {
+	if level, ok := state.perPackageLevel[pkg]; ok {
+		return level, pkg, true
+	}
+	if level, ok := state.perPackageLevel[path]; ok && path != "" {
+		return level, path, true
+	}
+
+	if h.testConvenience {
+		if base, isTest := strings.CutSuffix(pkg, "_test"); isTest {
+			if level, ok := state.perPackageLevel[base]; ok {
+				return level, base, true
+			}
+		}
+	}
+
+	if wildcard, ok := state.matchWildcard(path); ok {
+		return wildcard.level, wildcard.key, true
+	}
+	if glob, ok := state.matchGlob(path); ok {
+		return glob.level, glob.key, true
+	}
+
+	return 0, "", false
 }
 
-// parseFilter parses the filter specified in the ENV var.
-// The filter can consist of comman separated filters.
-// A filter specifies a package and a filter level, if the package is omitted,
-// the filter level is used as the default.
-//
-// This will set the log level to info for all logs
-// GO_LOG=info
-//
-// This will set the log level to error by default, but debug for mypackage and info for otherpackage
-// GO_LOG=error,mypackage=debug,otherpackage=info
-//
-// Filters later in the list have higher precedence over ones earlier in the list.
-func parseFilter(defaultLevel slog.Level, filter string) (slog.Level, map[string]slog.Level) {
-	perPackageLevel := make(map[string]slog.Level)
-
-	filters := strings.Split(filter, ",")
-	for _, filter := range filters {
-		first, second, ok := strings.Cut(filter, "=")
-		if !ok {
-			defaultLevel.UnmarshalText([]byte(first))
-			continue
+// resolvePackage returns the package name and full import path of the function containing pc.
+// If fullPackagePath is set, the package is identified by its full import path instead of its name.
+func (h *Handler) resolvePackage(pc uintptr) (pkg, path string, ok bool) {
+	resolved := h.resolveCaller(pc)
+	return resolved.pkg, resolved.path, resolved.ok
+}
+
+// resolveCaller returns the package, function and file containing pc.
+// A pc of zero, as in records created without a caller, is unresolvable.
+func (h *Handler) resolveCaller(pc uintptr) resolvedPackage {
+	if pc == 0 {
+		return resolvedPackage{}
+	}
+
+	if h.packages != nil {
+		if cached, hit := h.packages.Load(pc); hit {
+			return cached.(resolvedPackage)
+		}
+	}
+
+	f := callerFrame(pc)
+	cacheable := true
+	if h.skipStdlibFrames && isStdlibFunction(f.Function) {
+		// The caller outside the standard library depends on the stack rather than pc, so it can't be cached.
+		cacheable = false
+		if caller, ok := callerSkippingStdlib(pc); ok {
+			f = caller
 		}
+	}
 
-		packageLevel := perPackageLevel[first]
-		packageLevel.UnmarshalText([]byte(second))
-		perPackageLevel[first] = packageLevel
+	resolved := resolvedPackage{function: f.Function, file: f.File}
+	resolved.path, _ = packagePath(f.Function)
+	if h.packageResolver != nil && f.Function != "" {
+		resolved.pkg, resolved.ok = h.packageResolver(f)
+	} else {
+		resolved.pkg, resolved.ok = parsePackage(f.Function)
+		if h.fullPackagePath {
+			resolved.pkg = resolved.path
+		}
 	}
 
-	return defaultLevel, perPackageLevel
+	if cacheable && h.packages != nil {
+		if _, loaded := h.packages.LoadOrStore(pc, resolved); !loaded && h.ambiguous != nil {
+			h.ambiguous.check(h, h.state.Load())
+		}
+	}
+	return resolved
+}
+
+// resolvedPackage is a package resolved from a program counter, along with the function and file containing it.
+// Each logging call site has its own program counter, so caching them is bounded by the number of call sites
+// in the binary. They don't depend on the levels, so cached packages stay valid across runtime level changes
+// and reloads, and only matching them against the current filter is repeated for each record.
+type resolvedPackage struct {
+	pkg      string
+	path     string
+	function string
+	file     string
+	ok       bool
+}
+
+// recordPackage returns the package name and full import path of the record, resolving them if they weren't
+// needed to decide the record's level.
+func (h *Handler) recordPackage(record slog.Record, res resolution) (string, string) {
+	if res.pkg != "" {
+		return res.pkg, res.path
+	}
+
+	// The package isn't resolved for records outside the band.
+	pkg, path, _ := h.resolvePackage(record.PC)
+	return pkg, path
+}
+
+// remapLevel applies the level remapping for the record's package.
+func (h *Handler) remapLevel(record slog.Record, res resolution) slog.Record {
+	pkg, _ := h.recordPackage(record, res)
+	if to, ok := h.levelRemap[pkg][record.Level]; ok {
+		record.Level = to
+	}
+	return record
+}
+
+// levelBand returns the lowest and highest levels configured by the default level and package filters.
+func levelBand(defaultLevel slog.Level, perPackageLevel map[string]slog.Level) (slog.Level, slog.Level) {
+	minLevel, maxLevel := defaultLevel, defaultLevel
+	for _, level := range perPackageLevel {
+		minLevel = min(minLevel, level)
+		maxLevel = max(maxLevel, level)
+	}
+	return minLevel, maxLevel
+}
+
+// parsePackage parses the package out of a formatted function name, as the last element of its import path.
+// Example:
+// github.com/cbrewster/slog-env_test.TestFilterPackage
+// Will return slog-env_test
+// See packagePath for the function name formats handled.
+func parsePackage(function string) (string, bool) {
+	path, ok := packagePath(function)
+	if !ok {
+		return "", false
+	}
+	return path[strings.LastIndex(path, "/")+1:], true
 }
@@ -1,45 +1,31 @@
 package slogenv_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"testing/slogtest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	slogenv "github.com/cbrewster/slog-env"
+	shadowtestpackage "github.com/cbrewster/slog-env/internal/shadow/testpackage"
 	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/internal/testpackage/nested"
+	"github.com/cbrewster/slog-env/internal/testpackage/nested/deeper"
+	"github.com/cbrewster/slog-env/slogenvtest"
 )
 
-// testHandler provides a simple log handler which just records logs messages.
-type testHandler struct {
-	messages []string
-}
-
-// Enabled implements slog.Handler.
-func (*testHandler) Enabled(context.Context, slog.Level) bool {
-	return true
-}
-
-// Handle implements slog.Handler.
-func (h *testHandler) Handle(ctx context.Context, record slog.Record) error {
-	h.messages = append(h.messages, record.Message)
-	return nil
-}
-
-// WithAttrs implements slog.Handler.
-func (h *testHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return h
-}
-
-// WithGroup implements slog.Handler.
-func (h *testHandler) WithGroup(name string) slog.Handler {
-	return h
-}
-
-var _ slog.Handler = (*testHandler)(nil)
-
 // TestDefaultLevel tests setting just the default level.
 func TestDefaultLevel(t *testing.T) {
 	for _, test := range []struct {
@@ -68,16 +54,297 @@ func TestDefaultLevel(t *testing.T) {
 			os.Setenv("GO_LOG", test.filter)
 			defer os.Unsetenv("GO_LOG")
 
-			h := testHandler{}
-			logger := slog.New(slogenv.NewHandler(&h))
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h))
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Warn("warn")
+			logger.Error("error")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
+
+// TestEnvVarNames tests that the first set environment variable is used.
+func TestEnvVarNames(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		env       map[string]string
+		opts      []slogenv.Opt
+		wantLevel slog.Level
+	}{
+		{
+			name:      "second only",
+			env:       map[string]string{"LOG_LEVEL": "debug"},
+			opts:      []slogenv.Opt{slogenv.WithEnvVarNames("GO_LOG", "LOG_LEVEL")},
+			wantLevel: slog.LevelDebug,
+		},
+		{
+			name:      "first wins",
+			env:       map[string]string{"GO_LOG": "warn", "LOG_LEVEL": "debug"},
+			opts:      []slogenv.Opt{slogenv.WithEnvVarNames("GO_LOG", "LOG_LEVEL")},
+			wantLevel: slog.LevelWarn,
+		},
+		{
+			name:      "empty is skipped",
+			env:       map[string]string{"GO_LOG": "", "LOG_LEVEL": "error"},
+			opts:      []slogenv.Opt{slogenv.WithEnvVarNames("GO_LOG", "LOG_LEVEL")},
+			wantLevel: slog.LevelError,
+		},
+		{
+			name:      "none set",
+			opts:      []slogenv.Opt{slogenv.WithEnvVarNames("GO_LOG", "LOG_LEVEL")},
+			wantLevel: slog.LevelInfo,
+		},
+		{
+			name:      "last option wins",
+			env:       map[string]string{"GO_LOG": "warn", "LOG_LEVEL": "debug"},
+			opts:      []slogenv.Opt{slogenv.WithEnvVarNames("GO_LOG", "LOG_LEVEL"), slogenv.WithEnvVarName("LOG_LEVEL")},
+			wantLevel: slog.LevelDebug,
+		},
+		{
+			name:      "last option wins reversed",
+			env:       map[string]string{"APP_LOG": "error", "LOG_LEVEL": "debug"},
+			opts:      []slogenv.Opt{slogenv.WithEnvVarName("LOG_LEVEL"), slogenv.WithEnvVarNames("APP_LOG")},
+			wantLevel: slog.LevelError,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, name := range []string{"GO_LOG", "LOG_LEVEL", "APP_LOG"} {
+				t.Setenv(name, test.env[name])
+			}
+
+			handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(), test.opts...)
+			assert.Equal(t, test.wantLevel, handler.DefaultLevel())
+		})
+	}
+}
+
+// TestOverrideEnvVar tests that the override environment variable takes precedence over every other filter source.
+func TestOverrideEnvVar(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		override     string
+		opts         []slogenv.Opt
+		wantMessages []string
+	}{
+		{
+			name:         "quiet",
+			override:     "error",
+			wantMessages: []string{"error"},
+		},
+		{
+			name:         "verbose",
+			override:     "debug",
+			wantMessages: []string{"debug", "info", "warn", "error", "testpackage debug"},
+		},
+		{
+			name:         "over default filter",
+			override:     "warn",
+			opts:         []slogenv.Opt{slogenv.WithDefaultFilter("debug"), slogenv.WithMergeEnv(true)},
+			wantMessages: []string{"warn", "error"},
+		},
+		{
+			name:         "unset",
+			override:     "",
+			wantMessages: []string{"info", "warn", "error", "testpackage debug"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("GO_LOG", "info,testpackage=debug")
+			t.Setenv("SLOG_FORCE", test.override)
+
+			h := slogenvtest.NewCaptureHandler()
+			opts := append([]slogenv.Opt{slogenv.WithOverrideEnvVar("SLOG_FORCE")}, test.opts...)
+			logger := slog.New(slogenv.NewHandler(h, opts...))
 			logger.Debug("debug")
 			logger.Info("info")
 			logger.Warn("warn")
 			logger.Error("error")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+
+	t.Setenv("SLOG_FORCE", "info,acme=loud")
+	_, err := slogenv.NewHandlerWithError(slogenvtest.NewCaptureHandler(), slogenv.WithOverrideEnvVar("SLOG_FORCE"))
+	assert.EqualError(t, err, `invalid level "loud" for package "acme"`)
+}
+
+// TestWithoutEnv tests that no environment variable is read with WithoutEnv.
+func TestWithoutEnv(t *testing.T) {
+	t.Setenv("GO_LOG", "debug")
+	t.Setenv("APP_LOG", "debug")
+	t.Setenv("SLOG_FORCE", "debug")
+	t.Setenv("GO_LOG_COMPACT", slogenv.EncodeCompactFilter("debug"))
+
+	for _, test := range []struct {
+		name         string
+		opts         []slogenv.Opt
+		wantMessages []string
+	}{
+		{
+			name:         "default",
+			wantMessages: []string{"info", "warn"},
+		},
+		{
+			name:         "default filter",
+			opts:         []slogenv.Opt{slogenv.WithDefaultFilter("warn,testpackage=debug"), slogenv.WithMergeEnv(true)},
+			wantMessages: []string{"warn", "testpackage debug"},
+		},
+		{
+			name:         "default level and package levels",
+			opts:         []slogenv.Opt{slogenv.WithDefaultLevel(slog.LevelWarn), slogenv.WithPackageLevels(map[string]slog.Level{"testpackage": slog.LevelDebug})},
+			wantMessages: []string{"warn", "testpackage debug"},
+		},
+		{
+			name: "other variables",
+			opts: []slogenv.Opt{
+				slogenv.WithEnvVarName("APP_LOG"),
+				slogenv.WithOverrideEnvVar("SLOG_FORCE"),
+				slogenv.WithCompactFilterEnvVar("GO_LOG_COMPACT"),
+			},
+			wantMessages: []string{"info", "warn"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			h := slogenvtest.NewCaptureHandler()
+			opts := append([]slogenv.Opt{slogenv.WithoutEnv()}, test.opts...)
+			handler, err := slogenv.NewHandlerWithError(h, opts...)
+			require.NoError(t, err)
+			logger := slog.New(handler)
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Warn("warn")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
+
+// TestMergeEnv tests that the environment variable is applied on top of the default filter when merging.
+func TestMergeEnv(t *testing.T) {
+	for _, test := range []struct {
+		env          string
+		merge        bool
+		wantMessages []string
+		wantLevels   []slogenv.PackageLevel
+	}{
+		{
+			env:          "",
+			merge:        true,
+			wantMessages: []string{"warn", "testpackage info"},
+			wantLevels:   []slogenv.PackageLevel{{Package: "db", Level: slog.LevelError}, {Package: "testpackage", Level: slog.LevelInfo}},
+		},
+		{
+			env:          "testpackage=debug",
+			merge:        true,
+			wantMessages: []string{"warn", "testpackage debug", "testpackage info"},
+			wantLevels:   []slogenv.PackageLevel{{Package: "db", Level: slog.LevelError}, {Package: "testpackage", Level: slog.LevelDebug}},
+		},
+		{
+			env:          `"info,cache=warn"`,
+			merge:        true,
+			wantMessages: []string{"info", "warn", "testpackage info"},
+			wantLevels: []slogenv.PackageLevel{
+				{Package: "cache", Level: slog.LevelWarn},
+				{Package: "db", Level: slog.LevelError},
+				{Package: "testpackage", Level: slog.LevelInfo},
+			},
+		},
+		{
+			env:          "testpackage=debug",
+			merge:        false,
+			wantMessages: []string{"info", "warn", "testpackage debug", "testpackage info"},
+			wantLevels:   []slogenv.PackageLevel{{Package: "testpackage", Level: slog.LevelDebug}},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s/%t", test.env, test.merge), func(t *testing.T) {
+			t.Setenv("GO_LOG", test.env)
+
+			h := slogenvtest.NewCaptureHandler()
+			handler := slogenv.NewHandler(h,
+				slogenv.WithDefaultFilter("warn,db=error,testpackage=info"),
+				slogenv.WithMergeEnv(test.merge),
+			)
+			logger := slog.New(handler)
+			logger.Info("info")
+			logger.Warn("warn")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+			testpackage.LogSomething(logger, slog.LevelInfo, "testpackage info")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+			assert.Equal(t, test.wantLevels, handler.EffectiveLevels())
+		})
+	}
+}
+
+// TestPackageLevels tests levels set in code with WithPackageLevels, and how the environment variable
+// and the default filter are applied on top of them.
+func TestPackageLevels(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		env           string
+		defaultFilter string
+		merge         bool
+		wantMessages  []string
+		wantLevels    []slogenv.PackageLevel
+	}{
+		{
+			name:         "unset",
+			wantMessages: []string{"warn", "testpackage info"},
+			wantLevels:   []slogenv.PackageLevel{{Package: "db", Level: slog.LevelError}, {Package: "testpackage", Level: slog.LevelInfo}},
+		},
+		{
+			name:         "env",
+			env:          "info,cache=warn",
+			wantMessages: []string{"info", "warn", "testpackage info"},
+			wantLevels:   []slogenv.PackageLevel{{Package: "cache", Level: slog.LevelWarn}},
+		},
+		{
+			name:         "merged env",
+			env:          "info,testpackage=debug",
+			merge:        true,
+			wantMessages: []string{"info", "warn", "testpackage debug", "testpackage info"},
+			wantLevels:   []slogenv.PackageLevel{{Package: "db", Level: slog.LevelError}, {Package: "testpackage", Level: slog.LevelDebug}},
+		},
+		{
+			name:          "default filter",
+			defaultFilter: "info,db=debug",
+			wantMessages:  []string{"info", "warn", "testpackage info"},
+			wantLevels:    []slogenv.PackageLevel{{Package: "db", Level: slog.LevelDebug}, {Package: "testpackage", Level: slog.LevelInfo}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("GO_LOG", test.env)
+
+			h := slogenvtest.NewCaptureHandler()
+			handler, err := slogenv.NewHandlerWithError(h,
+				slogenv.WithDefaultLevel(slog.LevelWarn),
+				slogenv.WithPackageLevels(map[string]slog.Level{"db": slog.LevelError}),
+				slogenv.WithPackageLevels(map[string]slog.Level{"testpackage": slog.LevelInfo}),
+				slogenv.WithDefaultFilter(test.defaultFilter),
+				slogenv.WithMergeEnv(test.merge),
+			)
+			require.NoError(t, err)
+			logger := slog.New(handler)
+			logger.Info("info")
+			logger.Warn("warn")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+			testpackage.LogSomething(logger, slog.LevelInfo, "testpackage info")
 
-			assert.Equal(t, test.wantMessages, h.messages)
+			assert.Equal(t, test.wantMessages, h.Messages())
+			assert.Equal(t, test.wantLevels, handler.EffectiveLevels())
 		})
 	}
+
+	t.Setenv("GO_LOG", "")
+	_, err := slogenv.NewHandlerWithError(slogenvtest.NewCaptureHandler(),
+		slogenv.WithPackageLevels(map[string]slog.Level{"acme/v[/api": slog.LevelDebug}))
+	assert.EqualError(t, err, `invalid package pattern "acme/v[/api"`)
 }
 
 // TestPackageFilter tests both the default level and package filter.
@@ -107,8 +374,8 @@ func TestPackageFilter(t *testing.T) {
 			os.Setenv("GO_LOG", test.filter)
 			defer os.Unsetenv("GO_LOG")
 
-			h := testHandler{}
-			logger := slog.New(slogenv.NewHandler(&h))
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h))
 			logger.Debug("debug")
 			logger.Info("info")
 			logger.Warn("warn")
@@ -118,7 +385,992 @@ func TestPackageFilter(t *testing.T) {
 			testpackage.LogSomething(logger, slog.LevelWarn, "testpackage warn")
 			testpackage.LogSomething(logger, slog.LevelError, "testpackage error")
 
-			assert.Equal(t, test.wantMessages, h.messages)
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
+
+// TestWildcardPackageFilter tests that filters ending in * match packages by the prefix of their import path.
+func TestWildcardPackageFilter(t *testing.T) {
+	for _, test := range []struct {
+		filter       string
+		wantMessages []string
+	}{
+		{
+			filter:       "info,slog-env/internal/*=debug",
+			wantMessages: []string{"info", "testpackage debug", "nested debug"},
+		},
+		{
+			filter:       "info,internal/testpackage/*=debug",
+			wantMessages: []string{"info", "nested debug"},
+		},
+		{
+			// The longest prefix wins.
+			filter:       "info,internal/testpackage/*=debug,slog-env/internal/*=error",
+			wantMessages: []string{"info", "nested debug"},
+		},
+		{
+			// Exact matches take precedence over wildcards.
+			filter:       "info,github.com/cbrewster/slog-env*=debug,nested=error",
+			wantMessages: []string{"debug", "info", "testpackage debug"},
+		},
+		{
+			filter:       "info,github.com/cbrewster/slog-env/internal/testpackage/nested=debug",
+			wantMessages: []string{"info", "nested debug"},
+		},
+		{
+			// Prefixes only match from the start of a path element.
+			filter:       "info,ternal/*=debug",
+			wantMessages: []string{"info"},
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			t.Setenv("GO_LOG", test.filter)
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h))
+			logger.Debug("debug")
+			logger.Info("info")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+			nested.LogSomething(logger, slog.LevelDebug, "nested debug")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
+
+// TestHierarchicalPackages tests that package filters apply to subpackages unless they have a more specific filter.
+func TestHierarchicalPackages(t *testing.T) {
+	for _, test := range []struct {
+		filter       string
+		hierarchical bool
+		wantMessages []string
+	}{
+		{
+			filter:       "info,internal/testpackage=debug",
+			hierarchical: true,
+			wantMessages: []string{"testpackage debug", "nested debug", "nested warn", "deeper debug", "deeper warn"},
+		},
+		{
+			filter:       "info,testpackage=debug,nested=warn",
+			hierarchical: true,
+			wantMessages: []string{"testpackage debug", "nested warn", "deeper warn"},
+		},
+		{
+			// The middle level's filter is more specific for the deepest package, however it's ordered.
+			filter:       "info,testpackage/nested=warn,slog-env/internal/testpackage=debug",
+			hierarchical: true,
+			wantMessages: []string{"testpackage debug", "nested warn", "deeper warn"},
+		},
+		{
+			filter:       "info,testpackage=debug,nested/deeper=error",
+			hierarchical: true,
+			wantMessages: []string{"testpackage debug", "nested debug", "nested warn"},
+		},
+		{
+			filter:       "info,testpackage=debug,nested=warn",
+			wantMessages: []string{"testpackage debug", "nested warn", "deeper warn"},
+		},
+		{
+			filter:       "info,testpackage=debug",
+			wantMessages: []string{"testpackage debug", "nested warn", "deeper warn"},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s/%t", test.filter, test.hierarchical), func(t *testing.T) {
+			t.Setenv("GO_LOG", test.filter)
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h, slogenv.WithHierarchicalPackages(test.hierarchical)))
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+			nested.LogSomething(logger, slog.LevelDebug, "nested debug")
+			nested.LogSomething(logger, slog.LevelWarn, "nested warn")
+			deeper.LogSomething(logger, slog.LevelDebug, "deeper debug")
+			deeper.LogSomething(logger, slog.LevelWarn, "deeper warn")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
 		})
 	}
 }
+
+// TestModulePath tests that a filter for the path of the main module matches every package in the module.
+func TestModulePath(t *testing.T) {
+	for _, test := range []struct {
+		filter       string
+		wantMessages []string
+	}{
+		{
+			filter:       "warn,github.com/cbrewster/slog-env=debug",
+			wantMessages: []string{"testpackage debug", "nested debug", "deeper debug", "shadow debug"},
+		},
+		{
+			// Full import paths of packages which aren't modules only match the package itself.
+			filter:       "warn,github.com/cbrewster/slog-env/internal/testpackage=debug",
+			wantMessages: []string{"testpackage debug"},
+		},
+		{
+			// Filters for packages within the module are more specific, as is the exact package name.
+			filter:       "warn,github.com/cbrewster/slog-env=debug,github.com/cbrewster/slog-env/internal/testpackage/nested=error,deeper=info",
+			wantMessages: []string{"testpackage debug", "shadow debug"},
+		},
+		{
+			// The module path must match whole path elements.
+			filter:       "warn,github.com/cbrewster/slog=debug",
+			wantMessages: nil,
+		},
+		{
+			filter:       "warn,github.com/cbrewster/slog-env=debug,group:http=info",
+			wantMessages: []string{"testpackage debug", "nested debug", "deeper debug", "shadow debug"},
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			t.Setenv("GO_LOG", test.filter)
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h))
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+			nested.LogSomething(logger, slog.LevelDebug, "nested debug")
+			deeper.LogSomething(logger, slog.LevelDebug, "deeper debug")
+			shadowtestpackage.LogSomething(logger, slog.LevelDebug, "shadow debug")
+			logger.Debug("local debug")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
+
+// TestInlinedWrapper tests that records logged through small wrappers are attributed to the wrapper's package
+// even when the compiler inlines the wrapper into the caller. testpackage.LogSomething is small enough to be
+// inlined, while testpackage.LogSomethingNoInline never is.
+func TestInlinedWrapper(t *testing.T) {
+	for _, test := range []struct {
+		filter       string
+		wantMessages []string
+	}{
+		{
+			filter:       "warn,testpackage=debug",
+			wantMessages: []string{"inlined", "not inlined"},
+		},
+		{
+			filter:       "debug,testpackage=warn",
+			wantMessages: []string{"local"},
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			t.Setenv("GO_LOG", test.filter)
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h))
+			testpackage.LogSomething(logger, slog.LevelDebug, "inlined")
+			testpackage.LogSomethingNoInline(logger, slog.LevelDebug, "not inlined")
+			logger.Debug("local")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
+
+// TestFullPackagePath tests telling apart packages with the same name by their full import path.
+func TestFullPackagePath(t *testing.T) {
+	for _, test := range []struct {
+		filter       string
+		fullPath     bool
+		wantMessages []string
+	}{
+		{
+			filter:       "info,testpackage=debug",
+			wantMessages: []string{"testpackage debug", "shadow debug"},
+		},
+		{
+			filter:       "info,testpackage=debug",
+			fullPath:     true,
+			wantMessages: nil,
+		},
+		{
+			filter:       "info,github.com/cbrewster/slog-env/internal/testpackage=debug",
+			fullPath:     true,
+			wantMessages: []string{"testpackage debug"},
+		},
+		{
+			filter:       "info,github.com/cbrewster/slog-env/internal/shadow/testpackage=debug",
+			fullPath:     true,
+			wantMessages: []string{"shadow debug"},
+		},
+		{
+			filter:       "info,internal/*=debug",
+			fullPath:     true,
+			wantMessages: []string{"testpackage debug", "shadow debug"},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s/%t", test.filter, test.fullPath), func(t *testing.T) {
+			t.Setenv("GO_LOG", test.filter)
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h, slogenv.WithFullPackagePath(test.fullPath)))
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+			shadowtestpackage.LogSomething(logger, slog.LevelDebug, "shadow debug")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
+
+// TestTestConvenience tests that a filter for a package also covers its external test package.
+func TestTestConvenience(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		filter       string
+		enabled      bool
+		wantMessages []string
+	}{
+		{
+			name:         "disabled",
+			filter:       "slog-env=debug",
+			enabled:      false,
+			wantMessages: []string{"info", "testpackage info"},
+		},
+		{
+			name:         "enabled",
+			filter:       "slog-env=debug",
+			enabled:      true,
+			wantMessages: []string{"debug", "info", "testpackage info"},
+		},
+		{
+			name:         "test package filter wins",
+			filter:       "slog-env=debug,slog-env_test=error",
+			enabled:      true,
+			wantMessages: []string{"testpackage info"},
+		},
+		{
+			name:         "non-test package unaffected",
+			filter:       "testpackage=debug",
+			enabled:      true,
+			wantMessages: []string{"info", "testpackage debug", "testpackage info"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			os.Setenv("GO_LOG", test.filter)
+			defer os.Unsetenv("GO_LOG")
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h, slogenv.WithTestConvenience(test.enabled)))
+			logger.Debug("debug")
+			logger.Info("info")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+			testpackage.LogSomething(logger, slog.LevelInfo, "testpackage info")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
+
+// TestSetAsDefault tests that the default logger honors the filter after SetAsDefault.
+func TestSetAsDefault(t *testing.T) {
+	os.Setenv("GO_LOG", "warn,testpackage=debug")
+	defer os.Unsetenv("GO_LOG")
+
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.SetAsDefault(h)
+	assert.Same(t, handler, slog.Default().Handler())
+
+	slog.Info("info")
+	slog.Warn("warn")
+	testpackage.LogSomething(slog.Default(), slog.LevelDebug, "testpackage debug")
+
+	assert.Equal(t, []string{"warn", "testpackage debug"}, h.Messages())
+}
+
+// discardHandler is a log handler which drops every record.
+type discardHandler struct{}
+
+// Enabled implements slog.Handler.
+func (discardHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+// Handle implements slog.Handler.
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+
+// WithAttrs implements slog.Handler.
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+// WithGroup implements slog.Handler.
+func (h discardHandler) WithGroup(string) slog.Handler { return h }
+
+// BenchmarkHandle benchmarks logging records below, within, and above the band of levels
+// where package filters can change the outcome. Only records within the band resolve their package.
+func BenchmarkHandle(b *testing.B) {
+	ctx := context.Background()
+
+	for _, bench := range []struct {
+		name   string
+		filter string
+		level  slog.Level
+	}{
+		{name: "below band", filter: "info,testpackage=warn", level: slog.LevelDebug},
+		{name: "in band", filter: "info,testpackage=warn", level: slog.LevelInfo},
+		{name: "above band", filter: "info,testpackage=warn", level: slog.LevelError},
+		{name: "packages above default", filter: "info,testpackage=warn,db=error", level: slog.LevelInfo},
+		{name: "packages below default", filter: "info,testpackage=debug,db=debug", level: slog.LevelInfo},
+		{name: "no packages", filter: "info", level: slog.LevelInfo},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.Setenv("GO_LOG", bench.filter)
+			logger := slog.New(slogenv.NewHandler(discardHandler{}))
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				logger.Log(ctx, bench.level, "message")
+			}
+		})
+	}
+}
+
+// TestEnabledBand tests that Enabled reports records below every configured level as disabled.
+func TestEnabledBand(t *testing.T) {
+	os.Setenv("GO_LOG", "warn,testpackage=info")
+	defer os.Unsetenv("GO_LOG")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler())
+	ctx := context.Background()
+
+	assert.False(t, handler.Enabled(ctx, slog.LevelDebug))
+	assert.True(t, handler.Enabled(ctx, slog.LevelInfo))
+	assert.True(t, handler.Enabled(ctx, slog.LevelWarn))
+	assert.True(t, handler.Enabled(ctx, slog.LevelError))
+}
+
+// TestBandMatchesResolution tests that skipping package resolution outside the band doesn't change
+// which records are emitted, whether package levels are all above, all below, or on both sides of the default.
+func TestBandMatchesResolution(t *testing.T) {
+	for _, filter := range []string{
+		"warn",
+		"info,testpackage=warn",
+		"info,testpackage=debug",
+		"info,testpackage=debug,slog-env_test=error",
+	} {
+		t.Run(filter, func(t *testing.T) {
+			t.Setenv("GO_LOG", filter)
+
+			handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler())
+			ctx := context.Background()
+			for level := slog.LevelDebug - 4; level <= slog.LevelError+4; level++ {
+				for _, record := range []slog.Record{testpackage.Record(level, "testpackage"), recordHere(level, "here")} {
+					h := slogenvtest.NewCaptureHandler()
+					handler.SwapInner(h)
+					require.NoError(t, handler.Handle(ctx, record))
+
+					trace := handler.Trace(ctx, record)
+					assert.Equal(t, trace.Emitted, len(h.Messages()) == 1, "%s at %s", record.Message, level)
+					if trace.Emitted {
+						assert.True(t, handler.Enabled(ctx, level), "%s at %s", record.Message, level)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestEnabledPrecise tests that Enabled is exact when there are no package filters.
+func TestEnabledPrecise(t *testing.T) {
+	t.Setenv("GO_LOG", "warn")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler())
+	ctx := context.Background()
+	for level := slog.LevelDebug - 4; level <= slog.LevelError+4; level++ {
+		assert.Equal(t, level >= slog.LevelWarn, handler.Enabled(ctx, level), level)
+	}
+}
+
+// TestMessagePrefixFilter tests that message prefix filters only apply to matching messages.
+func TestMessagePrefixFilter(t *testing.T) {
+	os.Setenv("GO_LOG", "info")
+	defer os.Unsetenv("GO_LOG")
+
+	h := slogenvtest.NewCaptureHandler()
+	logger := slog.New(slogenv.NewHandler(h,
+		slogenv.WithMessagePrefixFilter("testpackage", "slow query", slog.LevelDebug),
+		slogenv.WithMessagePrefixFilter("testpackage", "health", slog.LevelWarn),
+	))
+	logger.Debug("slow query from test")
+	testpackage.LogSomething(logger, slog.LevelDebug, "slow query took 2s")
+	testpackage.LogSomething(logger, slog.LevelDebug, "fast query")
+	testpackage.LogSomething(logger, slog.LevelInfo, "health check ok")
+	testpackage.LogSomething(logger, slog.LevelInfo, "other info")
+
+	assert.Equal(t, []string{"slow query took 2s", "other info"}, h.Messages())
+}
+
+// TestUnresolvableLevel tests that records without a resolvable package use the unresolvable level.
+func TestUnresolvableLevel(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		opts         []slogenv.Opt
+		wantMessages []string
+	}{
+		{
+			name:         "default level",
+			wantMessages: []string{"no pc info", "info"},
+		},
+		{
+			name:         "unresolvable level",
+			opts:         []slogenv.Opt{slogenv.WithUnresolvableLevel(slog.LevelDebug)},
+			wantMessages: []string{"no pc debug", "no pc info", "info"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			os.Setenv("GO_LOG", "info,testpackage=error")
+			defer os.Unsetenv("GO_LOG")
+
+			h := slogenvtest.NewCaptureHandler()
+			handler := slogenv.NewHandler(h, test.opts...)
+			ctx := context.Background()
+
+			// Records created without a PC have no frame to resolve a package from.
+			for _, record := range []slog.Record{
+				slog.NewRecord(time.Now(), slog.LevelDebug, "no pc debug", 0),
+				slog.NewRecord(time.Now(), slog.LevelInfo, "no pc info", 0),
+			} {
+				if handler.Enabled(ctx, record.Level) {
+					assert.NoError(t, handler.Handle(ctx, record))
+				}
+			}
+
+			logger := slog.New(handler)
+			logger.Debug("debug")
+			logger.Info("info")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
+
+// TestPackageResolver tests resolving packages with a custom resolver.
+func TestPackageResolver(t *testing.T) {
+	t.Setenv("GO_LOG", "info,billing=debug,testpackage=error")
+
+	pc := testpackage.CallerPC()
+	// Match on the function name rather than the entry, since the entry of an inlined function is
+	// the entry of the function it was inlined into.
+	callerFunction := runtime.FuncForPC(pc - 1).Name()
+	resolver := slogenv.WithPackageResolver(func(frame runtime.Frame) (string, bool) {
+		switch {
+		case frame.Function == callerFunction:
+			return "billing", true
+		case strings.HasSuffix(frame.Function, ".LogSomething"):
+			return "", false
+		}
+		return "other", true
+	})
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h, resolver, slogenv.WithUnresolvableLevel(slog.LevelWarn))
+	assert.Equal(t, slog.LevelDebug, handler.LevelForPC(pc))
+
+	logger := slog.New(handler)
+	testpackage.LogSomething(logger, slog.LevelInfo, "unresolvable info")
+	testpackage.LogSomething(logger, slog.LevelWarn, "unresolvable warn")
+	testpackage.LogContext(context.Background(), logger, slog.LevelInfo, "other info")
+	assert.Equal(t, []string{"unresolvable warn", "other info"}, h.Messages())
+}
+
+// fanoutHandler is a composed log handler which forwards records to several handlers,
+// similar to the fan-out handlers provided by slog middleware libraries.
+type fanoutHandler struct {
+	handlers []slog.Handler
+}
+
+// Enabled implements slog.Handler.
+func (h *fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle implements slog.Handler.
+func (h *fanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, record.Level) {
+			errs = append(errs, handler.Handle(ctx, record.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return &fanoutHandler{handlers: handlers}
+}
+
+// WithGroup implements slog.Handler.
+func (h *fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &fanoutHandler{handlers: handlers}
+}
+
+// parseJSONLines parses each line written by a slog.JSONHandler.
+func parseJSONLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var results []map[string]any
+	for _, line := range bytes.Split(buf.Bytes(), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var result map[string]any
+		require.NoError(t, json.Unmarshal(line, &result))
+		results = append(results, result)
+	}
+	return results
+}
+
+// TestConformance runs the standard slog handler conformance tests against the handler,
+// both directly wrapping a handler and wrapping a composed handler.
+func TestConformance(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := slogenv.NewHandler(slog.NewJSONHandler(&buf, nil))
+		err := slogtest.TestHandler(handler, func() []map[string]any {
+			return parseJSONLines(t, &buf)
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("fanout", func(t *testing.T) {
+		var first, second bytes.Buffer
+		handler := slogenv.NewHandler(&fanoutHandler{handlers: []slog.Handler{
+			slog.NewJSONHandler(&first, nil),
+			slog.NewJSONHandler(&second, nil),
+		}})
+		err := slogtest.TestHandler(handler, func() []map[string]any {
+			return parseJSONLines(t, &first)
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, first.String(), second.String())
+	})
+}
+
+// TestFilterThroughComposition tests that package filters still apply through derived handlers
+// wrapping a composed inner handler.
+func TestFilterThroughComposition(t *testing.T) {
+	os.Setenv("GO_LOG", "warn,testpackage=debug")
+	defer os.Unsetenv("GO_LOG")
+
+	var first, second bytes.Buffer
+	handler := slogenv.NewHandler(&fanoutHandler{handlers: []slog.Handler{
+		slog.NewJSONHandler(&first, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		slog.NewJSONHandler(&second, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	}})
+	logger := slog.New(handler).With("request", "abc").WithGroup("http")
+	logger.Info("info", "path", "/")
+	testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+
+	for _, buf := range []*bytes.Buffer{&first, &second} {
+		results := parseJSONLines(t, buf)
+		require.Len(t, results, 1)
+		assert.Equal(t, "testpackage debug", results[0]["msg"])
+		assert.Equal(t, "abc", results[0]["request"])
+	}
+}
+
+// TestValidate tests validating filters without creating a handler.
+func TestValidate(t *testing.T) {
+	for _, test := range []struct {
+		filter  string
+		wantErr string
+	}{
+		{filter: ""},
+		{filter: "debug"},
+		{filter: "warn,db=debug,cache=error"},
+		{filter: "info+2,db=error-4"},
+		{filter: "infoo", wantErr: `invalid default level "infoo"`},
+		{filter: "info,db=loud", wantErr: `invalid level "loud" for package "db"`},
+		{filter: "db=", wantErr: `invalid level "" for package "db"`},
+		{filter: "nope,db=loud", wantErr: "invalid default level \"nope\"\ninvalid level \"loud\" for package \"db\""},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			err := slogenv.Validate(test.filter, slogenv.WithDefaultLevel(slog.LevelWarn))
+			if test.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.wantErr)
+			}
+		})
+	}
+}
+
+// TestNewHandlerWithError tests that malformed filters are reported while still returning a usable handler.
+func TestNewHandlerWithError(t *testing.T) {
+	for _, test := range []struct {
+		filter  string
+		wantErr string
+	}{
+		{filter: "warn,mypkg=debug"},
+		{filter: "mypkg=infoo", wantErr: `invalid level "infoo" for package "mypkg"`},
+		{filter: "mypkg=", wantErr: `invalid level "" for package "mypkg"`},
+		{filter: "=debug,=warn", wantErr: "empty package name in segment \"=debug\"\nempty package name in segment \"=warn\""},
+		{filter: "loud,testpackage=debug", wantErr: `invalid default level "loud"`},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			t.Setenv("GO_LOG", test.filter)
+
+			th := slogenvtest.NewCaptureHandler()
+			h, err := slogenv.NewHandlerWithError(th)
+			if test.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.wantErr)
+			}
+			require.NotNil(t, h)
+
+			testpackage.LogSomething(slog.New(h), slog.LevelWarn, "still logging")
+			assert.NotEmpty(t, th.Messages())
+		})
+	}
+}
+
+// recordAttrs returns the attributes of a record as a map of strings.
+func recordAttrs(record slog.Record) map[string]string {
+	attrs := make(map[string]string)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs[attr.Key] = attr.Value.String()
+		return true
+	})
+	return attrs
+}
+
+// TestWarnVerboseOverrides tests that only packages more verbose than the baseline are warned about.
+func TestWarnVerboseOverrides(t *testing.T) {
+	os.Setenv("GO_LOG", "debug,db=debug,cache=info,http=warn,auth=info-2")
+	defer os.Unsetenv("GO_LOG")
+
+	h := slogenvtest.NewCaptureHandler()
+	slogenv.NewHandler(h, slogenv.WithWarnVerboseOverrides(slog.LevelInfo))
+
+	require.Len(t, h.Records(), 2)
+	for _, record := range h.Records() {
+		assert.Equal(t, slog.LevelWarn, record.Level)
+	}
+	assert.Equal(t, map[string]string{"package": "auth", "level": "DEBUG+2", "baseline": "INFO"}, recordAttrs(h.Records()[0]))
+	assert.Equal(t, map[string]string{"package": "db", "level": "DEBUG", "baseline": "INFO"}, recordAttrs(h.Records()[1]))
+}
+
+// TestLogConfig tests that the resolved levels are logged once through the inner handler at construction.
+func TestLogConfig(t *testing.T) {
+	os.Setenv("GO_LOG", "warn,db=debug,http=error")
+	defer os.Unsetenv("GO_LOG")
+
+	h := slogenvtest.NewCaptureHandler()
+	slogenv.NewHandler(h, slogenv.WithLogConfig(true))
+
+	require.Len(t, h.Records(), 1)
+	record := h.Records()[0]
+	assert.Equal(t, slog.LevelInfo, record.Level)
+	assert.Equal(t, "slog-env: configuration", record.Message)
+
+	var packages map[string]string
+	record.Attrs(func(attr slog.Attr) bool {
+		switch attr.Key {
+		case "default":
+			assert.Equal(t, "WARN", attr.Value.String())
+		case "packages":
+			packages = make(map[string]string)
+			for _, pkg := range attr.Value.Group() {
+				packages[pkg.Key] = pkg.Value.String()
+			}
+		}
+		return true
+	})
+	assert.Equal(t, map[string]string{"db": "DEBUG", "http": "ERROR"}, packages)
+
+	t.Run("inner disabled", func(t *testing.T) {
+		var buf bytes.Buffer
+		slogenv.NewHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}), slogenv.WithLogConfig(true))
+		assert.Empty(t, buf.String())
+	})
+}
+
+// TestEffectiveLevels tests that package levels are returned in a stable, sorted order.
+func TestEffectiveLevels(t *testing.T) {
+	os.Setenv("GO_LOG", "info,zeta=debug,alpha=warn,mid=error,beta=info,gamma=debug")
+	defer os.Unsetenv("GO_LOG")
+
+	want := []slogenv.PackageLevel{
+		{Package: "alpha", Level: slog.LevelWarn},
+		{Package: "beta", Level: slog.LevelInfo},
+		{Package: "gamma", Level: slog.LevelDebug},
+		{Package: "mid", Level: slog.LevelError},
+		{Package: "zeta", Level: slog.LevelDebug},
+	}
+
+	for i := 0; i < 10; i++ {
+		handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler())
+		assert.Equal(t, want, handler.EffectiveLevels())
+	}
+}
+
+// TestLevelRemap tests that remapped levels are passed to the inner handler for matching packages only.
+func TestLevelRemap(t *testing.T) {
+	os.Setenv("GO_LOG", "warn,testpackage=info")
+	defer os.Unsetenv("GO_LOG")
+
+	h := slogenvtest.NewCaptureHandler()
+	logger := slog.New(slogenv.NewHandler(h,
+		slogenv.WithLevelRemap("testpackage", slog.LevelError, slog.LevelWarn),
+		slogenv.WithLevelRemap("testpackage", slog.LevelInfo, slog.LevelDebug),
+	))
+	logger.Error("error")
+	testpackage.LogSomething(logger, slog.LevelError, "testpackage error")
+	testpackage.LogSomething(logger, slog.LevelInfo, "testpackage info")
+	testpackage.LogSomething(logger, slog.LevelWarn, "testpackage warn")
+
+	require.Len(t, h.Records(), 4)
+	assert.Equal(t, slog.LevelError, h.Records()[0].Level)
+	assert.Equal(t, slog.LevelWarn, h.Records()[1].Level)
+	// Filtering is decided by the original level, so info is still emitted.
+	assert.Equal(t, slog.LevelDebug, h.Records()[2].Level)
+	assert.Equal(t, slog.LevelWarn, h.Records()[3].Level)
+}
+
+// TestSerializeInner tests that concurrent logging through a handler which isn't safe for
+// concurrent use doesn't lose records. Run with -race to also detect unsynchronized access.
+func TestSerializeInner(t *testing.T) {
+	os.Setenv("GO_LOG", "info,testpackage=debug")
+	defer os.Unsetenv("GO_LOG")
+
+	const goroutines, logsPerGoroutine = 8, 100
+
+	h := slogenvtest.NewCaptureHandler()
+	logger := slog.New(slogenv.NewHandler(h, slogenv.WithSerializeInner(true)))
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(logger *slog.Logger) {
+			defer wg.Done()
+			for j := 0; j < logsPerGoroutine; j++ {
+				logger.Info("info")
+				testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+			}
+		}(logger.With("goroutine", i))
+	}
+	wg.Wait()
+
+	assert.Len(t, h.Messages(), goroutines*logsPerGoroutine*2)
+}
+
+// TestWouldEverEnable tests querying whether a package could ever emit a record.
+func TestWouldEverEnable(t *testing.T) {
+	os.Setenv("GO_LOG", "warn,db=debug,noisy=error+4,cache=error,fatal=fatal,silent=off")
+	defer os.Unsetenv("GO_LOG")
+
+	levelFatal := slog.LevelError + 4
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(),
+		slogenv.WithMessagePrefixFilter("quiet", "important", slog.LevelInfo),
+		slogenv.WithLevelNames(map[string]slog.Level{"fatal": levelFatal}),
+	)
+
+	for _, test := range []struct {
+		pkg         string
+		wantEnabled bool
+		wantLevel   slog.Level
+	}{
+		{pkg: "db", wantEnabled: true, wantLevel: slog.LevelDebug},
+		{pkg: "github.com/acme/db", wantEnabled: true, wantLevel: slog.LevelDebug},
+		{pkg: "cache", wantEnabled: true, wantLevel: slog.LevelError},
+		// Levels above error can still be logged at.
+		{pkg: "noisy", wantEnabled: true, wantLevel: slog.LevelError + 4},
+		{pkg: "fatal", wantEnabled: true, wantLevel: levelFatal},
+		{pkg: "silent", wantEnabled: false, wantLevel: slogenv.LevelOff},
+		{pkg: "quiet", wantEnabled: true, wantLevel: slog.LevelInfo},
+		{pkg: "github.com/acme/quiet", wantEnabled: true, wantLevel: slog.LevelInfo},
+		{pkg: "other", wantEnabled: true, wantLevel: slog.LevelWarn},
+	} {
+		t.Run(test.pkg, func(t *testing.T) {
+			enabled, level := handler.WouldEverEnable(test.pkg)
+			assert.Equal(t, test.wantEnabled, enabled)
+			assert.Equal(t, test.wantLevel, level)
+		})
+	}
+}
+
+// TestWouldEverEnableTargets tests that function and file filters which could match code in a package lower
+// the minimum level reported for it.
+func TestWouldEverEnableTargets(t *testing.T) {
+	t.Setenv("GO_LOG", "warn,func:acme/api.HandleLogin=debug,file:db/conn.go=info,file:main.go=error")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler())
+	for _, test := range []struct {
+		pkg       string
+		wantLevel slog.Level
+	}{
+		{pkg: "api", wantLevel: slog.LevelDebug},
+		{pkg: "github.com/acme/api", wantLevel: slog.LevelDebug},
+		{pkg: "otheracme/api", wantLevel: slog.LevelWarn},
+		{pkg: "db", wantLevel: slog.LevelInfo},
+		{pkg: "other", wantLevel: slog.LevelWarn},
+	} {
+		t.Run(test.pkg, func(t *testing.T) {
+			enabled, level := handler.WouldEverEnable(test.pkg)
+			assert.True(t, enabled)
+			assert.Equal(t, test.wantLevel, level)
+		})
+	}
+}
+
+// TestWouldEverEnableAttrFilters tests that attribute filters for enabled keys lower the minimum level reported
+// for every package.
+func TestWouldEverEnableAttrFilters(t *testing.T) {
+	t.Setenv("GO_LOG", "warn,db=error,attr:component=billing=info,attr:unchecked=x=debug")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(), slogenv.WithAttrFilter("component"))
+	for _, pkg := range []string{"db", "other"} {
+		enabled, level := handler.WouldEverEnable(pkg)
+		assert.True(t, enabled, pkg)
+		assert.Equal(t, slog.LevelInfo, level, pkg)
+	}
+}
+
+// TestEnabledForPackage tests deciding whether records from a package would be emitted without logging them.
+func TestEnabledForPackage(t *testing.T) {
+	t.Setenv("GO_LOG", "warn,db=debug,acme/api/*=info,github.com/acme/config=error,group:http=debug")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler())
+	for _, test := range []struct {
+		pkg   string
+		level slog.Level
+		want  bool
+	}{
+		// Exact matches by name and by full import path.
+		{pkg: "db", level: slog.LevelDebug, want: true},
+		{pkg: "github.com/acme/db", level: slog.LevelDebug, want: true},
+		{pkg: "github.com/acme/config", level: slog.LevelWarn, want: false},
+		{pkg: "github.com/other/config", level: slog.LevelWarn, want: true},
+		// Wildcards.
+		{pkg: "github.com/acme/api/users", level: slog.LevelInfo, want: true},
+		{pkg: "github.com/acme/api/users", level: slog.LevelDebug, want: false},
+		// The default level.
+		{pkg: "cache", level: slog.LevelInfo, want: false},
+		{pkg: "cache", level: slog.LevelWarn, want: true},
+	} {
+		assert.Equal(t, test.want, handler.EnabledForPackage(test.pkg, test.level), "%s at %s", test.pkg, test.level)
+	}
+
+	// Group filters apply to grouped handlers.
+	grouped := handler.WithGroup("http").(*slogenv.Handler)
+	assert.True(t, grouped.EnabledForPackage("cache", slog.LevelDebug))
+	assert.False(t, handler.EnabledForPackage("cache", slog.LevelDebug))
+
+	// Changes at runtime apply.
+	require.NoError(t, handler.SetPackageLevel("cache", slog.LevelDebug))
+	assert.True(t, handler.EnabledForPackage("cache", slog.LevelDebug))
+}
+
+// TestLevelForPC tests resolving the level for program counters captured from different packages.
+func TestLevelForPC(t *testing.T) {
+	os.Setenv("GO_LOG", "warn,testpackage=debug")
+	defer os.Unsetenv("GO_LOG")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(), slogenv.WithUnresolvableLevel(slog.LevelError))
+
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+
+	assert.Equal(t, slog.LevelWarn, handler.LevelForPC(pcs[0]))
+	assert.Equal(t, slog.LevelDebug, handler.LevelForPC(testpackage.CallerPC()))
+	assert.Equal(t, slog.LevelError, handler.LevelForPC(0))
+}
+
+// panicHandler is a log handler which panics when handling a record.
+type panicHandler struct {
+	discardHandler
+}
+
+// Handle implements slog.Handler.
+func (panicHandler) Handle(context.Context, slog.Record) error {
+	panic("boom")
+}
+
+// WithAttrs implements slog.Handler.
+func (h panicHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+// WithGroup implements slog.Handler.
+func (h panicHandler) WithGroup(string) slog.Handler { return h }
+
+// TestRecoverInner tests recovering panics from the inner handler.
+func TestRecoverInner(t *testing.T) {
+	os.Unsetenv("GO_LOG")
+
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "info", 0)
+
+	handler := slogenv.NewHandler(panicHandler{}, slogenv.WithRecoverInner(true))
+	err := handler.Handle(context.Background(), record)
+	assert.EqualError(t, err, "slog-env: inner handler panicked: boom")
+
+	// Derived handlers recover too.
+	err = handler.WithAttrs([]slog.Attr{slog.String("key", "value")}).Handle(context.Background(), record)
+	assert.Error(t, err)
+
+	handler = slogenv.NewHandler(panicHandler{})
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = handler.Handle(context.Background(), record)
+	})
+}
+
+// errorHandler is a log handler which fails to handle every record.
+type errorHandler struct {
+	discardHandler
+	err error
+}
+
+// Handle implements slog.Handler.
+func (h errorHandler) Handle(context.Context, slog.Record) error {
+	return h.err
+}
+
+// WithAttrs implements slog.Handler.
+func (h errorHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+// WithGroup implements slog.Handler.
+func (h errorHandler) WithGroup(string) slog.Handler { return h }
+
+// TestErrorHandler tests that errors from the inner handler are passed to the error handler and still returned.
+func TestErrorHandler(t *testing.T) {
+	t.Setenv("GO_LOG", "info")
+
+	errDiskFull := errors.New("disk full")
+	var errs []error
+	onError := slogenv.WithErrorHandler(func(err error) {
+		errs = append(errs, err)
+	})
+
+	handler := slogenv.NewHandler(errorHandler{err: errDiskFull}, onError)
+	err := handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "info", 0))
+	assert.ErrorIs(t, err, errDiskFull)
+	assert.Equal(t, []error{errDiskFull}, errs)
+
+	// Dropped records and successfully handled ones don't call it.
+	_ = handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelDebug, "debug", 0))
+	_ = slogenv.NewHandler(slogenvtest.NewCaptureHandler(), onError).Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "info", 0))
+	assert.Len(t, errs, 1)
+
+	// Derived handlers call it too.
+	slog.New(handler).With("key", "value").WithGroup("g").Info("info")
+	assert.Equal(t, []error{errDiskFull, errDiskFull}, errs)
+
+	// Recovered panics are reported as errors.
+	errs = nil
+	handler = slogenv.NewHandler(panicHandler{}, slogenv.WithRecoverInner(true), onError)
+	err = handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "info", 0))
+	require.Len(t, errs, 1)
+	assert.Equal(t, err, errs[0])
+}
@@ -0,0 +1,53 @@
+package slogenv
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+// innerVersion is a version of the root inner handler. SwapInner replaces it as a whole,
+// so comparing pointers tells whether a derived handler is up to date.
+type innerVersion struct {
+	handler slog.Handler
+}
+
+// derivedInner is an inner handler derived from a version of the root inner handler.
+type derivedInner struct {
+	version *innerVersion
+	handler slog.Handler
+}
+
+// SwapInner atomically replaces the inner handler, for example to redirect output after rotating a log file.
+// The swap applies to this handler and every handler derived from the same NewHandler call, which re-apply
+// their WithAttrs and WithGroup calls to the new inner handler the next time they log.
+// In-flight calls to Handle finish with whichever inner handler they started with.
+func (h *Handler) SwapInner(inner slog.Handler) {
+	h.root.Store(&innerVersion{handler: inner})
+}
+
+// innerHandler returns the inner handler for this handler, re-deriving it if the root was swapped.
+func (h *Handler) innerHandler() slog.Handler {
+	version := h.root.Load()
+	cached := h.inner.Load()
+	if cached.version == version {
+		return cached.handler
+	}
+
+	handler := version.handler
+	for _, derive := range h.derivations {
+		handler = derive(handler)
+	}
+	h.inner.Store(&derivedInner{version: version, handler: handler})
+	return handler
+}
+
+// derive returns a copy of the handler with derivation applied to its inner handler.
+func (h *Handler) derive(derivation func(slog.Handler) slog.Handler) *Handler {
+	current := h.inner.Load()
+
+	derived := *h
+	derived.derivations = append(h.derivations[:len(h.derivations):len(h.derivations)], derivation)
+	derived.inner = &atomic.Pointer[derivedInner]{}
+	derived.inner.Store(&derivedInner{version: current.version, handler: derivation(current.handler)})
+	return &derived
+}
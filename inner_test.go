@@ -0,0 +1,108 @@
+package slogenv_test
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+)
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writes.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write implements io.Writer.
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// lines returns the lines written to the buffer.
+func (b *lockedBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return parseLines(b.buf.String())
+}
+
+// parseLines splits output into non-empty lines.
+func parseLines(output string) []string {
+	var lines []string
+	for _, line := range bytes.Split([]byte(output), []byte("\n")) {
+		if len(line) > 0 {
+			lines = append(lines, string(line))
+		}
+	}
+	return lines
+}
+
+// TestSwapInner tests that swapping the inner handler applies to derived handlers.
+func TestSwapInner(t *testing.T) {
+	os.Unsetenv("GO_LOG")
+
+	var first, second bytes.Buffer
+	handler := slogenv.NewHandler(slog.NewJSONHandler(&first, nil))
+	logger := slog.New(handler)
+	derived := logger.With("request", "abc").WithGroup("http")
+
+	logger.Info("before")
+	derived.Info("derived before", "path", "/")
+
+	handler.SwapInner(slog.NewJSONHandler(&second, nil))
+	logger.Info("after")
+	derived.Info("derived after", "path", "/")
+
+	assert.Equal(t, 2, len(parseLines(first.String())))
+	secondLines := parseLines(second.String())
+	require.Len(t, secondLines, 2)
+	assert.Contains(t, secondLines[0], `"msg":"after"`)
+	assert.Contains(t, secondLines[1], `"request":"abc","http":{"path":"/"}`)
+}
+
+// TestSwapInnerConcurrent swaps the inner handler while logging from many goroutines.
+// Every record must be written whole to exactly one of the inner handlers. Run with -race to detect torn state.
+func TestSwapInnerConcurrent(t *testing.T) {
+	os.Unsetenv("GO_LOG")
+
+	const goroutines, logsPerGoroutine, swaps = 8, 200, 50
+
+	buffers := make([]*lockedBuffer, swaps+1)
+	for i := range buffers {
+		buffers[i] = &lockedBuffer{}
+	}
+
+	handler := slogenv.NewHandler(slog.NewJSONHandler(buffers[0], nil))
+	logger := slog.New(handler).With("derived", true)
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < logsPerGoroutine; j++ {
+				logger.Info("message", "n", j)
+			}
+		}()
+	}
+	for i := 1; i <= swaps; i++ {
+		handler.SwapInner(slog.NewJSONHandler(buffers[i], nil))
+	}
+	wg.Wait()
+
+	total := 0
+	for _, buf := range buffers {
+		for _, line := range buf.lines() {
+			assert.Contains(t, line, `"msg":"message","derived":true,"n":`)
+			total++
+		}
+	}
+	assert.Equal(t, goroutines*logsPerGoroutine, total)
+}
@@ -0,0 +1,11 @@
+// Package testpackage shares its name with internal/testpackage, to test packages with the same leaf name.
+package testpackage
+
+import (
+	"context"
+	"log/slog"
+)
+
+func LogSomething(logger *slog.Logger, level slog.Level, message string) {
+	logger.Log(context.Background(), level, message)
+}
@@ -3,8 +3,33 @@ package testpackage
 import (
 	"context"
 	"log/slog"
+	"runtime"
+	"time"
 )
 
 func LogSomething(logger *slog.Logger, level slog.Level, message string) {
 	logger.Log(context.Background(), level, message)
 }
+
+//go:noinline
+func LogSomethingNoInline(logger *slog.Logger, level slog.Level, message string) {
+	logger.Log(context.Background(), level, message)
+}
+
+func LogContext(ctx context.Context, logger *slog.Logger, level slog.Level, message string) {
+	logger.Log(ctx, level, message)
+}
+
+func LogAttrs(logger *slog.Logger, level slog.Level, message string, attrs ...slog.Attr) {
+	logger.LogAttrs(context.Background(), level, message, attrs...)
+}
+
+func CallerPC() uintptr {
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	return pcs[0]
+}
+
+func Record(level slog.Level, message string) slog.Record {
+	return slog.NewRecord(time.Now(), level, message, CallerPC())
+}
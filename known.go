@@ -0,0 +1,69 @@
+package slogenv
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// WithKnownPackages sets the packages filters are allowed to refer to, so that NewHandlerWithError and Validate
+// report filters for any other package, such as a misspelled package name, instead of silently ignoring them.
+// Packages can be given by name or full import path. A filter is known if it names one of the packages, or
+// the end of one's import path following a slash, and wildcards, globs and module paths are known if they
+// match at least one of the packages. Group, function, file and attribute filters aren't checked.
+// Filters loaded after the handler was created, such as by WithFilterFile, aren't checked either.
+func WithKnownPackages(pkgs []string) Opt {
+	return func(cfg *config) {
+		cfg.knownPackages = pkgs
+	}
+}
+
+// checkKnownPackages returns an error naming each package filter in perPackageLevel which doesn't refer to
+// one of the known packages. It returns nil if no known packages are configured.
+func (cfg *config) checkKnownPackages(perPackageLevel map[string]slog.Level) error {
+	if cfg.knownPackages == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, key := range sortedPackages(perPackageLevel) {
+		if strings.HasPrefix(key, groupPrefix) || isTargetKey(key) || isAttrKey(key) {
+			continue
+		}
+		if !cfg.isKnownPackage(key) {
+			errs = append(errs, fmt.Errorf("unknown package %q", key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isKnownPackage reports whether the package filter key refers to at least one of the known packages.
+func (cfg *config) isKnownPackage(key string) bool {
+	var matches func(pkg string) bool
+	switch {
+	case isGlobKey(key):
+		matches = packageGlob{key: key}.match
+	case strings.HasSuffix(key, "*"):
+		matches = func(pkg string) bool {
+			_, ok := packageWildcard{key: key, prefix: strings.TrimSuffix(key, "*")}.match(pkg)
+			return ok
+		}
+	case cfg.hierarchicalPackages || cfg.isModuleKey(key):
+		matches = func(pkg string) bool {
+			_, ok := packageWildcard{key: key, prefix: key + "/", parent: true}.match(pkg)
+			return ok
+		}
+	default:
+		matches = func(pkg string) bool {
+			return pkg == key || strings.HasSuffix(pkg, "/"+key)
+		}
+	}
+
+	for _, pkg := range cfg.knownPackages {
+		if matches(pkg) {
+			return true
+		}
+	}
+	return false
+}
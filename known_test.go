@@ -0,0 +1,69 @@
+package slogenv_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestKnownPackages tests reporting filters for packages which aren't known.
+func TestKnownPackages(t *testing.T) {
+	known := slogenv.WithKnownPackages([]string{"github.com/acme/api", "github.com/acme/db", "github.com/acme/lib/v2/client", "cache"})
+
+	for _, test := range []struct {
+		filter  string
+		opts    []slogenv.Opt
+		wantErr string
+	}{
+		{filter: "info,api=debug,acme/db=warn,cache=+,github.com/acme/api=error"},
+		{filter: "info,acme/*=debug,acme/d?=warn"},
+		{
+			// github.com/acme/lib isn't a module in the build, so it only matches packages at that path.
+			filter:  "info,github.com/acme/lib=error",
+			wantErr: `unknown package "github.com/acme/lib"`,
+		},
+		{
+			filter: "info,github.com/acme/lib=error",
+			opts:   []slogenv.Opt{slogenv.WithHierarchicalPackages(true)},
+		},
+		{filter: "info,group:http=debug,func:acme/api.Get=debug,file:server.go=debug,attr:tenant=acme=debug"},
+		{filter: "info"},
+		{
+			filter:  "info,api=debug,dbb=warn",
+			wantErr: `unknown package "dbb"`,
+		},
+		{
+			filter:  "info,other/api=debug,pi=debug,acme/x*=warn,acme/v?=warn,github.com/acme/li=error",
+			wantErr: "unknown package \"acme/v?\"\nunknown package \"acme/x*\"\nunknown package \"github.com/acme/li\"\nunknown package \"other/api\"\nunknown package \"pi\"",
+		},
+		{
+			filter:  "info,acme=debug",
+			wantErr: `unknown package "acme"`,
+		},
+		{
+			filter: "info,acme=debug",
+			opts:   []slogenv.Opt{slogenv.WithHierarchicalPackages(true)},
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			t.Setenv("GO_LOG", test.filter)
+
+			opts := append([]slogenv.Opt{known}, test.opts...)
+			_, err := slogenv.NewHandlerWithError(slogenvtest.NewCaptureHandler(), opts...)
+			validateErr := slogenv.Validate(test.filter, opts...)
+			if test.wantErr == "" {
+				assert.NoError(t, err)
+				assert.NoError(t, validateErr)
+			} else {
+				assert.EqualError(t, err, test.wantErr)
+				assert.EqualError(t, validateErr, test.wantErr)
+			}
+		})
+	}
+
+	// Without known packages, any package is allowed.
+	assert.NoError(t, slogenv.Validate("info,dbb=warn"))
+}
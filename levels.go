@@ -0,0 +1,413 @@
+package slogenv
+
+import (
+	"errors"
+	"log/slog"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// levelState is a snapshot of the levels configured by the filter. Reloading the filter replaces it as a whole,
+// so a record is always resolved against a consistent set of levels.
+// A level state, including its maps, is never modified once it has been stored, so handlers derived with WithAttrs
+// and WithGroup can read it without locking while updateLevels prepares its replacement from a copy.
+type levelState struct {
+	// defaultLevel is the log level used for logs not matching one of the package filters.
+	defaultLevel slog.Level
+	// perPackageLevel stores the log level for each package.
+	// Filters for groups are stored alongside packages, with keys prefixed by group:.
+	perPackageLevel map[string]slog.Level
+	// perPackageOffset is the offset from the default level for packages with a relative level.
+	perPackageOffset map[string]slog.Level
+	// wildcards are the package filters ending in *, longest prefix first.
+	wildcards []packageWildcard
+	// globs are the package filters containing glob metacharacters, most specific first.
+	globs []packageGlob
+	// targets are the function and file filters, most specific first.
+	targets []targetFilter
+	// attrFilters is true if any filter is for an attribute value.
+	attrFilters bool
+	// unresolvableLevel is the level for records whose package can't be determined.
+	unresolvableLevel slog.Level
+	// minLevel and maxLevel bound the levels a package filter can change the outcome for.
+	// Records below minLevel are always dropped and records at or above maxLevel are always emitted,
+	// so the caller's package only needs to be resolved for records in between.
+	minLevel, maxLevel slog.Level
+	// leveled is the level of the default leveler when the state was stored, to detect changes to it.
+	leveled slog.Level
+}
+
+// newLevelState creates the level state for a parsed filter.
+func newLevelState(cfg *config, parsed parsedFilter) *levelState {
+	state := &levelState{
+		defaultLevel:      parsed.defaultLevel,
+		perPackageLevel:   parsed.perPackageLevel,
+		perPackageOffset:  parsed.perPackageOffset,
+		unresolvableLevel: parsed.defaultLevel,
+	}
+	if cfg.unresolvableLevel != nil {
+		state.unresolvableLevel = *cfg.unresolvableLevel
+	}
+	state.wildcards = packageWildcards(cfg, state.perPackageLevel)
+	state.globs = packageGlobs(state.perPackageLevel)
+	state.targets = targetFilters(state.perPackageLevel)
+	for key := range state.perPackageLevel {
+		if isAttrKey(key) {
+			state.attrFilters = true
+			break
+		}
+	}
+
+	state.minLevel, state.maxLevel = levelBand(state.defaultLevel, state.perPackageLevel)
+	for _, filters := range cfg.messagePrefixLevel {
+		for _, filter := range filters {
+			state.minLevel = min(state.minLevel, filter.level)
+			state.maxLevel = max(state.maxLevel, filter.level)
+		}
+	}
+	state.minLevel = min(state.minLevel, state.unresolvableLevel)
+	state.maxLevel = max(state.maxLevel, state.unresolvableLevel)
+	return state
+}
+
+// packageWildcard is a package filter with a trailing *, such as acme/api/*=debug, which matches
+// every package whose full import path starts with the prefix, either from its start or after a slash.
+// With WithHierarchicalPackages, every other package filter is also a wildcard for its subpackages,
+// which matches the package itself as well, as is every filter for a module path, see config.isModuleKey.
+type packageWildcard struct {
+	key    string
+	prefix string
+	level  slog.Level
+	// parent is true if the filter also matches the package named by key.
+	parent bool
+}
+
+// packageWildcards returns the wildcard filters in perPackageLevel, longest prefix first so ties between
+// equally specific matches are broken by the longest prefix.
+// Filters for module paths, or with WithHierarchicalPackages, all filters without a trailing *, are included as
+// wildcards for their subpackages.
+func packageWildcards(cfg *config, perPackageLevel map[string]slog.Level) []packageWildcard {
+	var wildcards []packageWildcard
+	for key, level := range perPackageLevel {
+		if strings.HasPrefix(key, groupPrefix) || isTargetKey(key) || isAttrKey(key) || isGlobKey(key) {
+			continue
+		}
+
+		if prefix, ok := strings.CutSuffix(key, "*"); ok {
+			wildcards = append(wildcards, packageWildcard{key: key, prefix: prefix, level: level})
+		} else if cfg.hierarchicalPackages || cfg.isModuleKey(key) {
+			wildcards = append(wildcards, packageWildcard{key: key, prefix: key + "/", level: level, parent: true})
+		}
+	}
+
+	sort.Slice(wildcards, func(i, j int) bool {
+		if len(wildcards[i].prefix) != len(wildcards[j].prefix) {
+			return len(wildcards[i].prefix) > len(wildcards[j].prefix)
+		}
+		return wildcards[i].key < wildcards[j].key
+	})
+	return wildcards
+}
+
+// isModuleKey reports whether a package filter key is the path of the main module or one of its dependencies,
+// such as golang.org/x/net, as recorded in the build info. A filter for a module path matches every package
+// in the module. Module paths are also recognized without their major version suffix, so a filter for
+// github.com/acme/lib matches github.com/acme/lib/v2/client too.
+func (cfg *config) isModuleKey(key string) bool {
+	return cfg.modules[key]
+}
+
+// buildModules returns the paths of the main module and its dependencies from the build info, along with
+// the same paths without a major version suffix. It returns nil if the binary has no build info.
+var buildModules = sync.OnceValue(func() map[string]bool {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+
+	modules := make(map[string]bool)
+	add := func(path string) {
+		if path == "" {
+			return
+		}
+		modules[path] = true
+		if prefix, ok := trimMajorVersion(path); ok {
+			modules[prefix] = true
+		}
+	}
+	add(info.Main.Path)
+	for _, dep := range info.Deps {
+		add(dep.Path)
+	}
+	return modules
+})
+
+// trimMajorVersion returns the module path without its major version suffix, such as /v2, if it has one.
+func trimMajorVersion(path string) (string, bool) {
+	i := strings.LastIndex(path, "/v")
+	if i < 0 || i+2 == len(path) {
+		return "", false
+	}
+	for _, c := range path[i+2:] {
+		if c < '0' || c > '9' {
+			return "", false
+		}
+	}
+	return path[:i], true
+}
+
+// match reports whether the wildcard matches the full import path, and how far into the path the match ends.
+// Matches which end further into the path are more specific.
+func (w packageWildcard) match(path string) (int, bool) {
+	if path == "" {
+		return 0, false
+	}
+	if w.parent && (path == w.key || strings.HasSuffix(path, "/"+w.key)) {
+		return len(path) + 1, true
+	}
+	if i := strings.LastIndex(path, "/"+w.prefix); i >= 0 {
+		return i + 1 + len(w.prefix), true
+	}
+	if strings.HasPrefix(path, w.prefix) {
+		return len(w.prefix), true
+	}
+	return 0, false
+}
+
+// matchWildcard returns the most specific wildcard matching the full import path.
+func (s *levelState) matchWildcard(path string) (packageWildcard, bool) {
+	var best packageWildcard
+	bestEnd := -1
+	for _, wildcard := range s.wildcards {
+		if end, ok := wildcard.match(path); ok && end > bestEnd {
+			best, bestEnd = wildcard, end
+		}
+	}
+	return best, bestEnd >= 0
+}
+
+// filter returns a copy of the filter the level state was created from, which can be modified
+// to create a new level state.
+func (s *levelState) filter() parsedFilter {
+	parsed := parsedFilter{
+		defaultLevel:     s.defaultLevel,
+		perPackageLevel:  make(map[string]slog.Level, len(s.perPackageLevel)),
+		perPackageOffset: make(map[string]slog.Level, len(s.perPackageOffset)),
+	}
+	for pkg, level := range s.perPackageLevel {
+		parsed.perPackageLevel[pkg] = level
+	}
+	for pkg, offset := range s.perPackageOffset {
+		parsed.perPackageOffset[pkg] = offset
+	}
+	return parsed
+}
+
+// groupMatch is the group filter matching a handler's group path for a version of the level state.
+type groupMatch struct {
+	state *levelState
+	rule  string
+	level slog.Level
+}
+
+// levels returns the current level state, shared by all handlers derived from the same NewHandler call.
+// If the default leveler changed since the state was created, the state is updated to match it.
+func (h *Handler) levels() *levelState {
+	state := h.state.Load()
+	if l := h.cfg.defaultLeveler; l != nil && l.Level() != state.leveled {
+		// Following the leveler isn't a runtime update, so it isn't checked.
+		h.stateMu.Lock()
+		h.storeLevels(h.nextLevels(func(*parsedFilter) {}))
+		h.stateMu.Unlock()
+		return h.state.Load()
+	}
+	return state
+}
+
+// groupFilter returns the key and level of the group filter matching this handler's group path in state,
+// re-matching it if the state was replaced since it was last matched.
+func (h *Handler) groupFilter(state *levelState) (string, slog.Level) {
+	if len(h.groups) == 0 {
+		return "", 0
+	}
+
+	cached := h.group.Load()
+	if cached != nil && cached.state == state {
+		return cached.rule, cached.level
+	}
+
+	rule, level := matchGroup(state.perPackageLevel, h.groups)
+	h.group.Store(&groupMatch{state: state, rule: rule, level: level})
+	return rule, level
+}
+
+// updateLevels applies update to a copy of the current filter and replaces the level state
+// with the result, for this handler and every handler derived from the same NewHandler call.
+// If the result is rejected by checkLevels, the level state is left unchanged and the error is returned.
+func (h *Handler) updateLevels(update func(*parsedFilter)) error {
+	h.stateMu.Lock()
+	defer h.stateMu.Unlock()
+
+	return h.replaceLevels(h.nextLevels(update))
+}
+
+// nextLevels returns the level state resulting from applying update to a copy of the current filter.
+// Must be called with stateMu held.
+func (h *Handler) nextLevels(update func(*parsedFilter)) *levelState {
+	state := h.state.Load()
+	parsed := state.filter()
+	if l := h.cfg.defaultLeveler; l != nil {
+		if level := l.Level(); level != state.leveled {
+			parsed.defaultLevel = level
+		}
+	}
+	update(&parsed)
+	parsed.resolveOffsets()
+	return newLevelState(h.cfg, parsed)
+}
+
+// replaceLevels replaces the level state with state at runtime, unless checkLevels rejects it.
+// Must be called with stateMu held.
+func (h *Handler) replaceLevels(state *levelState) error {
+	if err := h.checkLevels(state); err != nil {
+		return err
+	}
+	old := h.state.Load()
+	h.storeLevels(state)
+	h.subscribers.notify(old, state)
+	if h.ambiguous != nil {
+		h.ambiguous.check(h, state)
+	}
+	return nil
+}
+
+// checkLevels returns an error if a runtime update to state isn't allowed.
+func (h *Handler) checkLevels(state *levelState) error {
+	if h.baseline != nil {
+		if err := checkVerbosity(h.baseline, state); err != nil {
+			return err
+		}
+	}
+	if validate := h.cfg.reloadValidation; validate != nil {
+		return validate(handlerConfig(state))
+	}
+	return nil
+}
+
+// levelSetter is a slog.Leveler whose level can be set, such as *slog.LevelVar.
+type levelSetter interface {
+	Set(level slog.Level)
+}
+
+// storeLevels replaces the level state, keeping the default leveler in sync with it if it can be set.
+// Callers other than NewHandler must hold stateMu.
+func (h *Handler) storeLevels(state *levelState) {
+	if l := h.cfg.defaultLeveler; l != nil {
+		if setter, ok := l.(levelSetter); ok {
+			setter.Set(state.defaultLevel)
+		}
+		state.leveled = l.Level()
+	}
+	h.state.Store(state)
+}
+
+// DefaultLevel returns the level currently used for packages without a filter.
+func (h *Handler) DefaultLevel() slog.Level {
+	return h.levels().defaultLevel
+}
+
+// MinLevel returns the lowest level any record can be emitted at: the most verbose of the default level and
+// every package, group, function, file and message prefix filter. Records below it are always dropped,
+// so callers can check it to skip building expensive attributes. Levels overridden in a context with
+// WithLevelOverride aren't included.
+func (h *Handler) MinLevel() slog.Level {
+	return h.levels().minLevel
+}
+
+// PackageLevels returns a copy of the level currently set for each package and group filter.
+// Changes to the returned map don't affect the handler.
+func (h *Handler) PackageLevels() map[string]slog.Level {
+	state := h.levels()
+	levels := make(map[string]slog.Level, len(state.perPackageLevel))
+	for pkg, level := range state.perPackageLevel {
+		levels[pkg] = level
+	}
+	return levels
+}
+
+// SetDefaultLevel sets the level for packages without a filter. Packages with a level relative
+// to the default, such as mypkg=+, follow the new default.
+// If the change isn't allowed, such as by WithAllowRuntimeVerbosityIncrease, nothing is changed and an error
+// is returned, as it is by the other setters.
+// It is safe to call while other goroutines are logging, and applies to every handler derived from this one.
+func (h *Handler) SetDefaultLevel(level slog.Level) error {
+	return h.updateLevels(func(parsed *parsedFilter) {
+		parsed.defaultLevel = level
+	})
+}
+
+// SetPackageLevel sets the level for pkg, replacing any filter for it. Like in filters,
+// pkg can be prefixed with group: to set the level for a group path.
+// If pkg is a glob which isn't a valid pattern, or the change isn't allowed, nothing is changed and an error
+// is returned.
+// It is safe to call while other goroutines are logging, and applies to every handler derived from this one.
+func (h *Handler) SetPackageLevel(pkg string, level slog.Level) error {
+	if err := checkPackagePattern(pkg); err != nil {
+		return err
+	}
+
+	return h.updateLevels(func(parsed *parsedFilter) {
+		delete(parsed.perPackageOffset, pkg)
+		parsed.perPackageLevel[pkg] = level
+	})
+}
+
+// RemovePackageLevel removes the filter for pkg, so it uses the default level again.
+func (h *Handler) RemovePackageLevel(pkg string) error {
+	return h.updateLevels(func(parsed *parsedFilter) {
+		delete(parsed.perPackageOffset, pkg)
+		delete(parsed.perPackageLevel, pkg)
+	})
+}
+
+// UpdateLevels applies several changes to the levels as a single update, so no record is resolved against only
+// some of them. If defaultLevel isn't nil, it becomes the default level. Each package in packages is set to its
+// level, replacing any filter for it, or has its filter removed if its level is nil.
+// If any package is a glob which isn't a valid pattern, or the changes aren't allowed, nothing is changed
+// and an error is returned.
+// It is safe to call while other goroutines are logging, and applies to every handler derived from this one.
+func (h *Handler) UpdateLevels(defaultLevel *slog.Level, packages map[string]*slog.Level) error {
+	var errs []error
+	for _, pkg := range sortedPackages(packages) {
+		if err := checkPackagePattern(pkg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return h.updateLevels(func(parsed *parsedFilter) {
+		if defaultLevel != nil {
+			parsed.defaultLevel = *defaultLevel
+		}
+		for pkg, level := range packages {
+			delete(parsed.perPackageOffset, pkg)
+			if level == nil {
+				delete(parsed.perPackageLevel, pkg)
+			} else {
+				parsed.perPackageLevel[pkg] = *level
+			}
+		}
+	})
+}
+
+// ClearPackageLevels removes every package and group filter, so all packages use the default level.
+func (h *Handler) ClearPackageLevels() error {
+	return h.updateLevels(func(parsed *parsedFilter) {
+		clear(parsed.perPackageOffset)
+		clear(parsed.perPackageLevel)
+	})
+}
@@ -0,0 +1,72 @@
+package slogenv
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestModuleKeyMatch tests matching filters for module paths against the import paths of packages in them.
+func TestModuleKeyMatch(t *testing.T) {
+	cfg := newConfig()
+	cfg.modules = map[string]bool{"golang.org/x/net": true, "github.com/acme/lib": true, "github.com/acme/lib2": true}
+	state := newLevelState(&cfg, parsedFilter{
+		defaultLevel: slog.LevelInfo,
+		perPackageLevel: map[string]slog.Level{
+			"golang.org/x/net":     slog.LevelWarn,
+			"github.com/acme/lib":  slog.LevelError,
+			"github.com/acme/lib2": slog.LevelDebug,
+		},
+	})
+
+	for path, want := range map[string]string{
+		"golang.org/x/net":                   "golang.org/x/net",
+		"golang.org/x/net/http2":             "golang.org/x/net",
+		"golang.org/x/net/http2/hpack":       "golang.org/x/net",
+		"golang.org/x/network":               "",
+		"github.com/acme/lib/v2":             "github.com/acme/lib",
+		"github.com/acme/lib/v3/client":      "github.com/acme/lib",
+		"github.com/acme/lib2/client":        "github.com/acme/lib2",
+		"github.com/acme/library":            "",
+		"github.com/other/golang.org/x/net2": "",
+	} {
+		wildcard, ok := state.matchWildcard(path)
+		assert.Equal(t, want != "", ok, path)
+		assert.Equal(t, want, wildcard.key, path)
+	}
+}
+
+// TestIsModuleKey tests that only the paths of the main module and its dependencies are module paths.
+func TestIsModuleKey(t *testing.T) {
+	cfg := newConfig()
+	for key, want := range map[string]bool{
+		"github.com/cbrewster/slog-env":                      true,
+		"github.com/stretchr/testify":                        true,
+		"gopkg.in/yaml.v3":                                   true,
+		"github.com/cbrewster/slog-env/internal/testpackage": false,
+		"golang.org/x/net":                                   false,
+		"acme/api":                                           false,
+		"testpackage":                                        false,
+		"group:github.com/cbrewster/slog-env":                false,
+		"file:server.go":                                     false,
+	} {
+		assert.Equal(t, want, cfg.isModuleKey(key), key)
+	}
+}
+
+// TestTrimMajorVersion tests removing the major version suffix from module paths.
+func TestTrimMajorVersion(t *testing.T) {
+	for path, want := range map[string]string{
+		"github.com/acme/lib/v2":  "github.com/acme/lib",
+		"github.com/acme/lib/v10": "github.com/acme/lib",
+		"github.com/acme/lib":     "",
+		"github.com/acme/lib/v":   "",
+		"github.com/acme/vendor":  "",
+		"gopkg.in/yaml.v3":        "",
+	} {
+		trimmed, ok := trimMajorVersion(path)
+		assert.Equal(t, want != "", ok, path)
+		assert.Equal(t, want, trimmed, path)
+	}
+}
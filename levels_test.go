@@ -0,0 +1,351 @@
+package slogenv_test
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestCurrentLevels tests reading back the levels the handler is using.
+func TestCurrentLevels(t *testing.T) {
+	t.Setenv("GO_LOG", "warn,db=debug,cache=+,group:http=error")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler())
+	assert.Equal(t, slog.LevelWarn, handler.DefaultLevel())
+	assert.Equal(t, map[string]slog.Level{
+		"db":         slog.LevelDebug,
+		"cache":      slog.LevelInfo,
+		"group:http": slog.LevelError,
+	}, handler.PackageLevels())
+
+	levels := handler.PackageLevels()
+	levels["db"] = slog.LevelError
+	delete(levels, "cache")
+	assert.Equal(t, slog.LevelDebug, handler.PackageLevels()["db"])
+	assert.Contains(t, handler.PackageLevels(), "cache")
+
+	require.NoError(t, handler.SetDefaultLevel(slog.LevelError))
+	assert.Equal(t, slog.LevelError, handler.DefaultLevel())
+	assert.Equal(t, slog.LevelWarn, handler.PackageLevels()["cache"])
+}
+
+// TestMinLevel tests that the minimum level accounts for every filter and follows changes at runtime.
+func TestMinLevel(t *testing.T) {
+	t.Setenv("GO_LOG", "error,db=debug,cache=warn")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler())
+	assert.Equal(t, slog.LevelDebug, handler.MinLevel())
+
+	require.NoError(t, handler.SetPackageLevel("db", slog.LevelError))
+	assert.Equal(t, slog.LevelWarn, handler.MinLevel())
+
+	require.NoError(t, handler.SetPackageLevel("group:http", slog.LevelDebug-2))
+	assert.Equal(t, slog.LevelDebug-2, handler.MinLevel())
+
+	require.NoError(t, handler.ClearPackageLevels())
+	assert.Equal(t, slog.LevelError, handler.MinLevel())
+
+	require.NoError(t, handler.SetDefaultLevel(slog.LevelInfo))
+	assert.Equal(t, slog.LevelInfo, handler.MinLevel())
+
+	// Silenced packages don't raise the minimum above the default.
+	require.NoError(t, handler.SetPackageLevel("noisy", slogenv.LevelOff))
+	assert.Equal(t, slog.LevelInfo, handler.MinLevel())
+}
+
+// TestDefaultLevelVar tests that changes to the default level variable apply to the handler.
+func TestDefaultLevelVar(t *testing.T) {
+	t.Setenv("GO_LOG", "testpackage=debug,cache=+")
+
+	var v slog.LevelVar
+	v.Set(slog.LevelWarn)
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h, slogenv.WithDefaultLevel(slog.LevelDebug), slogenv.WithDefaultLevelVar(&v))
+	logger := slog.New(handler).WithGroup("g")
+	ctx := context.Background()
+
+	logger.Info("before")
+	assert.False(t, handler.Enabled(ctx, slog.LevelDebug-1))
+
+	v.Set(slog.LevelInfo)
+	logger.Info("after")
+	testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+	assert.Equal(t, slog.LevelInfo, handler.DefaultLevel())
+	// Relative levels follow the variable.
+	assert.Equal(t, slog.LevelDebug, handler.PackageLevels()["cache"])
+
+	v.Set(slog.LevelError)
+	assert.False(t, handler.Enabled(ctx, slog.LevelDebug-1))
+	logger.Warn("warn")
+
+	require.NoError(t, handler.SetDefaultLevel(slog.LevelDebug))
+	assert.Equal(t, slog.LevelDebug, v.Level())
+	logger.Debug("debug")
+
+	assert.Equal(t, []string{"after", "testpackage debug", "debug"}, h.Messages())
+}
+
+// TestDefaultLevelVarFromFilter tests that a default level in the filter is stored in the level variable.
+func TestDefaultLevelVarFromFilter(t *testing.T) {
+	t.Setenv("GO_LOG", "error,testpackage=debug")
+
+	var v slog.LevelVar
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(), slogenv.WithDefaultLevelVar(&v))
+	assert.Equal(t, slog.LevelError, v.Level())
+	assert.Equal(t, slog.LevelError, handler.DefaultLevel())
+}
+
+// dynamicLeveler is a read-only slog.Leveler whose level can change.
+type dynamicLeveler struct {
+	level atomic.Int64
+}
+
+func (l *dynamicLeveler) Level() slog.Level { return slog.Level(l.level.Load()) }
+
+// TestDefaultLeveler tests using fixed and changing slog.Levelers for the default level.
+func TestDefaultLeveler(t *testing.T) {
+	var v slog.LevelVar
+	v.Set(slog.LevelWarn)
+	var dynamic dynamicLeveler
+	dynamic.level.Store(int64(slog.LevelWarn))
+
+	for _, test := range []struct {
+		name      string
+		leveler   slog.Leveler
+		env       string
+		wantLevel slog.Level
+	}{
+		{name: "level", leveler: slog.LevelWarn, wantLevel: slog.LevelWarn},
+		{name: "level var", leveler: &v, wantLevel: slog.LevelWarn},
+		{name: "dynamic", leveler: &dynamic, wantLevel: slog.LevelWarn},
+		{name: "level with env", leveler: slog.LevelWarn, env: "debug", wantLevel: slog.LevelDebug},
+		{name: "level with package env", leveler: slog.LevelWarn, env: "testpackage=info", wantLevel: slog.LevelWarn},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("GO_LOG", test.env)
+
+			handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(),
+				slogenv.WithDefaultLevel(slog.LevelError),
+				slogenv.WithDefaultLeveler(test.leveler),
+			)
+			assert.Equal(t, test.wantLevel, handler.DefaultLevel())
+
+			// Changing other levels keeps the default level.
+			require.NoError(t, handler.SetPackageLevel("db", slog.LevelDebug))
+			assert.Equal(t, test.wantLevel, handler.DefaultLevel())
+		})
+	}
+
+	t.Setenv("GO_LOG", "error,cache=+")
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h, slogenv.WithDefaultLeveler(&dynamic))
+	logger := slog.New(handler)
+
+	// The filter's default level takes precedence until the leveler changes.
+	logger.Warn("filter")
+	assert.Equal(t, slog.LevelError, handler.DefaultLevel())
+
+	dynamic.level.Store(int64(slog.LevelInfo))
+	logger.Info("leveler")
+	assert.Equal(t, slog.LevelInfo, handler.DefaultLevel())
+	assert.Equal(t, slog.LevelDebug, handler.PackageLevels()["cache"])
+
+	// SetDefaultLevel also takes precedence until the leveler changes, which it can't store in.
+	require.NoError(t, handler.SetDefaultLevel(slog.LevelDebug))
+	logger.Debug("set")
+	assert.Equal(t, slog.LevelInfo, dynamic.Level())
+
+	dynamic.level.Store(int64(slog.LevelWarn))
+	logger.Info("dropped")
+	logger.Warn("changed")
+
+	assert.Equal(t, []string{"leveler", "set", "changed"}, h.Messages())
+}
+
+// TestSetPackageLevel tests that changing levels at runtime starts and stops messages flowing
+// through the handler and handlers derived from it.
+func TestSetPackageLevel(t *testing.T) {
+	t.Setenv("GO_LOG", "info,cache=+")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h)
+	logger := slog.New(handler).With("request", "abc")
+
+	testpackage.LogSomething(logger, slog.LevelDebug, "before")
+
+	require.NoError(t, handler.SetPackageLevel("testpackage", slog.LevelDebug))
+	testpackage.LogSomething(logger, slog.LevelDebug, "package debug")
+
+	// Invalid globs are rejected.
+	assert.EqualError(t, handler.SetPackageLevel("a[", slog.LevelDebug), `invalid package pattern "a["`)
+	assert.NotContains(t, handler.PackageLevels(), "a[")
+
+	require.NoError(t, handler.SetDefaultLevel(slog.LevelWarn))
+	logger.Info("default info")
+	testpackage.LogSomething(logger, slog.LevelDebug, "still debug")
+
+	require.NoError(t, handler.RemovePackageLevel("testpackage"))
+	testpackage.LogSomething(logger, slog.LevelInfo, "removed info")
+	testpackage.LogSomething(logger, slog.LevelWarn, "removed warn")
+
+	assert.Equal(t, []string{"package debug", "still debug", "removed warn"}, h.Messages())
+	// Relative levels follow the new default.
+	assert.Equal(t, []slogenv.PackageLevel{{Package: "cache", Level: slog.LevelInfo}}, handler.EffectiveLevels())
+
+	require.NoError(t, handler.ClearPackageLevels())
+	assert.Empty(t, handler.EffectiveLevels())
+}
+
+// TestUpdateLevels tests applying several level changes at once, and that nothing is changed if a package
+// pattern is invalid.
+func TestUpdateLevels(t *testing.T) {
+	t.Setenv("GO_LOG", "info,db=warn,cache=+")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler())
+	debug, errorLevel := slog.LevelDebug, slog.LevelError
+
+	require.NoError(t, handler.UpdateLevels(&debug, map[string]*slog.Level{
+		"db":       nil,
+		"cache":    &errorLevel,
+		"acme/*/x": &debug,
+	}))
+	assert.Equal(t, slog.LevelDebug, handler.DefaultLevel())
+	assert.Equal(t, map[string]slog.Level{"cache": slog.LevelError, "acme/*/x": slog.LevelDebug}, handler.PackageLevels())
+
+	err := handler.UpdateLevels(&errorLevel, map[string]*slog.Level{"db": &debug, "a[": &debug})
+	assert.EqualError(t, err, `invalid package pattern "a["`)
+	assert.Equal(t, slog.LevelDebug, handler.DefaultLevel())
+	assert.Equal(t, map[string]slog.Level{"cache": slog.LevelError, "acme/*/x": slog.LevelDebug}, handler.PackageLevels())
+}
+
+// TestDerivedHandlersShareLevels tests that level changes made through any handler in a tree derived
+// with WithGroup and WithAttrs apply to the whole tree.
+func TestDerivedHandlersShareLevels(t *testing.T) {
+	t.Setenv("GO_LOG", "info")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h)
+	child := handler.WithGroup("child").(*slogenv.Handler)
+	sibling := handler.WithAttrs([]slog.Attr{slog.String("sibling", "true")}).(*slogenv.Handler)
+
+	require.NoError(t, handler.SetDefaultLevel(slog.LevelDebug))
+	slog.New(child).Debug("child debug")
+	assert.Equal(t, slog.LevelDebug, child.DefaultLevel())
+
+	require.NoError(t, child.SetDefaultLevel(slog.LevelWarn))
+	slog.New(sibling).Info("sibling info")
+	slog.New(handler).Info("parent info")
+	assert.Equal(t, slog.LevelWarn, handler.DefaultLevel())
+
+	require.NoError(t, sibling.SetPackageLevel("testpackage", slog.LevelDebug))
+	testpackage.LogSomething(slog.New(child), slog.LevelDebug, "child testpackage debug")
+	assert.Equal(t, handler.PackageLevels(), child.PackageLevels())
+
+	assert.Equal(t, []string{"child debug", "child testpackage debug"}, h.Messages())
+}
+
+// TestSetGroupLevel tests that group filters set at runtime apply to existing grouped handlers.
+func TestSetGroupLevel(t *testing.T) {
+	t.Setenv("GO_LOG", "info")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h)
+	grouped := slog.New(handler).WithGroup("http")
+
+	grouped.Debug("before")
+	require.NoError(t, handler.SetPackageLevel("group:http", slog.LevelDebug))
+	grouped.Debug("after")
+	require.NoError(t, handler.RemovePackageLevel("group:http"))
+	grouped.Debug("removed")
+
+	assert.Equal(t, []string{"after"}, h.Messages())
+}
+
+// TestSetLevelConcurrent tests that levels can be changed while other goroutines are logging.
+func TestSetLevelConcurrent(t *testing.T) {
+	t.Setenv("GO_LOG", "info")
+
+	handler := slogenv.NewHandler(discardHandler{})
+	logger := slog.New(handler)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				testpackage.LogSomething(logger, slog.LevelDebug, "debug")
+				logger.WithGroup("http").Info("info")
+			}
+		}()
+	}
+
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, handler.SetPackageLevel("testpackage", slog.Level(i%8-4)))
+		require.NoError(t, handler.SetDefaultLevel(slog.Level(i%12-4)))
+		require.NoError(t, handler.RemovePackageLevel("testpackage"))
+	}
+	wg.Wait()
+
+	assert.True(t, handler.Enabled(context.Background(), slog.LevelError))
+}
+
+// TestDeriveConcurrent tests that handlers can be derived and logged to from many goroutines while levels
+// are being changed, which is only free of data races if the level state is never modified in place.
+func TestDeriveConcurrent(t *testing.T) {
+	t.Setenv("GO_LOG", "info,db=warn")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				logger := slog.New(handler.WithAttrs([]slog.Attr{slog.Int("goroutine", i)}))
+				if j%2 == 0 {
+					logger = logger.WithGroup("http")
+				}
+				testpackage.LogSomething(logger, slog.LevelDebug, "debug")
+				logger.Error("error")
+
+				levels := logger.Handler().(*slogenv.Handler).PackageLevels()
+				levels["scratch"] = slog.LevelDebug
+			}
+		}(i)
+	}
+
+	for i := 0; i < 500; i++ {
+		require.NoError(t, handler.SetPackageLevel("testpackage", slog.Level(i%8-4)))
+		require.NoError(t, handler.SetPackageLevel("group:http", slog.Level(i%12-4)))
+		require.NoError(t, handler.RemovePackageLevel("db"))
+		require.NoError(t, handler.SetDefaultLevel(slog.Level(i%12-4)))
+	}
+	wg.Wait()
+
+	assert.NotContains(t, handler.PackageLevels(), "scratch")
+	assert.Equal(t, 8*500, countMessages(h.Messages(), "error"))
+}
+
+// countMessages returns the number of messages equal to msg.
+func countMessages(messages []string, msg string) int {
+	var n int
+	for _, m := range messages {
+		if m == msg {
+			n++
+		}
+	}
+	return n
+}
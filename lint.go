@@ -0,0 +1,102 @@
+package slogenv
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Lint returns a human-readable warning for each package filter in the current filter which is redundant,
+// because the filter that would apply without it sets the same level, or unreachable, because a filter with
+// higher precedence always matches first. For example, with GO_LOG=info,acme/*=debug,acme/api=debug,acme/v?/db=warn
+// both acme/api and acme/v?/db are reported. Group, function, file and attribute filters aren't checked.
+// The warnings are sorted by the filter they are for, and nil is returned if there are none.
+func (h *Handler) Lint() []string {
+	state := h.levels()
+
+	var warnings []string
+	for _, key := range sortedPackages(state.perPackageLevel) {
+		if strings.HasPrefix(key, groupPrefix) || isTargetKey(key) || isAttrKey(key) {
+			continue
+		}
+		level := state.perPackageLevel[key]
+		rule := formatRule(key, "", level)
+
+		if isGlobKey(key) {
+			if wildcard, ok := state.shadowingWildcard(key); ok {
+				warnings = append(warnings, fmt.Sprintf("%s is unreachable: %s takes precedence for every package it matches",
+					rule, formatRule(wildcard.key, "", wildcard.level)))
+			} else if level == state.defaultLevel {
+				warnings = append(warnings, fmt.Sprintf("%s is redundant: it sets the default level", rule))
+			}
+			continue
+		}
+
+		// Filters for package names only take precedence over full import paths when packages are identified
+		// by their name.
+		if short := path.Base(key); !h.fullPackagePath && short != key && !strings.HasSuffix(key, "*") {
+			if shortLevel, ok := state.perPackageLevel[short]; ok {
+				shortRule := formatRule(short, "", shortLevel)
+				if shortLevel == level {
+					warnings = append(warnings, fmt.Sprintf("%s is redundant: %s already sets the same level", rule, shortRule))
+				} else {
+					warnings = append(warnings, fmt.Sprintf("%s is unreachable: %s takes precedence for the package %s",
+						rule, shortRule, short))
+				}
+				continue
+			}
+		}
+
+		// A wildcard can override globs matching some of the same packages, so it's only redundant with
+		// the default level if there are no globs.
+		fallback, ok := state.fallbackFilter(key)
+		if ok && state.perPackageLevel[fallback] == level {
+			warnings = append(warnings, fmt.Sprintf("%s is redundant: %s already sets the same level",
+				rule, formatRule(fallback, "", level)))
+		} else if !ok && level == state.defaultLevel && state.perPackageOffset[key] == 0 &&
+			!(strings.HasSuffix(key, "*") && len(state.globs) > 0) {
+			warnings = append(warnings, fmt.Sprintf("%s is redundant: it sets the default level", rule))
+		}
+	}
+	return warnings
+}
+
+// shadowingWildcard returns the wildcard in s which matches every package the glob key can match, making it
+// unreachable, since wildcards take precedence over globs. Only the part of the glob before its first
+// metacharacter is considered, so a wildcard shadowing it can be missed, but is never reported wrongly.
+func (s *levelState) shadowingWildcard(glob string) (packageWildcard, bool) {
+	literal := glob[:strings.IndexAny(glob, "*?[")]
+	for _, wildcard := range s.wildcards {
+		if strings.HasPrefix(literal, wildcard.prefix) || strings.Contains(literal, "/"+wildcard.prefix) {
+			return wildcard, true
+		}
+	}
+	return packageWildcard{}, false
+}
+
+// fallbackFilter returns the key of the filter which would apply to the package or wildcard key if its own
+// filter was removed: the most specific other wildcard matching it, or for packages, the first glob matching it.
+func (s *levelState) fallbackFilter(key string) (string, bool) {
+	prefix, isWildcard := strings.CutSuffix(key, "*")
+
+	var best packageWildcard
+	bestEnd := -1
+	for _, wildcard := range s.wildcards {
+		if wildcard.key == key {
+			continue
+		}
+		if end, ok := wildcard.match(prefix); ok && end > bestEnd {
+			best, bestEnd = wildcard, end
+		}
+	}
+	if bestEnd >= 0 {
+		return best.key, true
+	}
+
+	if !isWildcard {
+		if glob, ok := s.matchGlob(key); ok {
+			return glob.key, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,92 @@
+package slogenv_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestLint tests the warnings for redundant and unreachable filters.
+func TestLint(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		filter string
+		opts   []slogenv.Opt
+		want   []string
+	}{
+		{
+			name:   "clean",
+			filter: "info,acme/*=debug,acme/api=warn,db=error,group:http=debug",
+		},
+		{
+			name:   "package under wildcard",
+			filter: "info,acme/*=debug,acme/api=debug",
+			want:   []string{"acme/api=DEBUG is redundant: acme/*=DEBUG already sets the same level"},
+		},
+		{
+			name:   "nested wildcards",
+			filter: "info,acme/*=debug,acme/api/*=debug,acme/api/v1=warn",
+			want:   []string{"acme/api/*=DEBUG is redundant: acme/*=DEBUG already sets the same level"},
+		},
+		{
+			name:   "default level",
+			filter: "info,db=info,cache=debug",
+			want:   []string{"db=INFO is redundant: it sets the default level"},
+		},
+		{
+			name:   "relative level",
+			filter: "info,db=+,cache=-",
+		},
+		{
+			name:   "short name and path",
+			filter: "info,api=debug,acme/api=warn,acme/db=error,db=error",
+			want: []string{
+				"acme/api=WARN is unreachable: api=DEBUG takes precedence for the package api",
+				"acme/db=ERROR is redundant: db=ERROR already sets the same level",
+			},
+		},
+		{
+			// Names don't match full import paths when packages are identified by their path.
+			name:   "full package path",
+			filter: "info,api=debug,acme/api=warn,acme/db=error,db=error",
+			opts:   []slogenv.Opt{slogenv.WithFullPackagePath(true)},
+		},
+		{
+			name:   "glob under wildcard",
+			filter: "info,acme/*=warn,acme/v?/api=debug,other/v?/api=debug",
+			want:   []string{"acme/v?/api=DEBUG is unreachable: acme/*=WARN takes precedence for every package it matches"},
+		},
+		{
+			name:   "glob at default level",
+			filter: "info,acme/v?/api=info",
+			want:   []string{"acme/v?/api=INFO is redundant: it sets the default level"},
+		},
+		{
+			name:   "wildcard overriding glob",
+			filter: "info,acme/*=info,*/api=debug",
+		},
+		{
+			name:   "package under glob",
+			filter: "info,acme/v?/api=debug,acme/v1/api=debug",
+			want:   []string{"acme/v1/api=DEBUG is redundant: acme/v?/api=DEBUG already sets the same level"},
+		},
+		{
+			name:   "hierarchical",
+			filter: "info,acme=debug,acme/api=debug,acme/db=warn",
+			opts:   []slogenv.Opt{slogenv.WithHierarchicalPackages(true)},
+			want:   []string{"acme/api=DEBUG is redundant: acme=DEBUG already sets the same level"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("GO_LOG", test.filter)
+
+			handler, err := slogenv.NewHandlerWithError(slogenvtest.NewCaptureHandler(), test.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, handler.Lint())
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package slogenv
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithRateLimit limits records from pkg below error to perSecond per second, using a token bucket which
+// allows bursts of up to perSecond records. Errors and more severe records are never limited.
+// Rate limiting applies after filtering, and is measured by the time of each record, or the current time
+// for records without one. Use RateLimited to see how many records were suppressed.
+func WithRateLimit(pkg string, perSecond int) Opt {
+	return func(cfg *config) {
+		if cfg.rateLimits == nil {
+			cfg.rateLimits = make(map[string]int)
+		}
+		cfg.rateLimits[pkg] = perSecond
+	}
+}
+
+// rateLimiter is a token bucket limiting the records from a package.
+type rateLimiter struct {
+	rate float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	// suppressed is the number of records dropped by the limiter.
+	suppressed atomic.Int64
+}
+
+// newRateLimiters creates the rate limiters for each package with a positive rate.
+func newRateLimiters(rateLimits map[string]int) map[string]*rateLimiter {
+	var limiters map[string]*rateLimiter
+	for pkg, perSecond := range rateLimits {
+		if perSecond <= 0 {
+			continue
+		}
+		if limiters == nil {
+			limiters = make(map[string]*rateLimiter)
+		}
+		limiters[pkg] = &rateLimiter{rate: float64(perSecond), tokens: float64(perSecond)}
+	}
+	return limiters
+}
+
+// allow reports whether a record at level logged at t is within the rate, taking a token if it is.
+func (l *rateLimiter) allow(level slog.Level, t time.Time) bool {
+	if level >= slog.LevelError {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() && t.After(l.last) {
+		l.tokens = min(l.rate, l.tokens+t.Sub(l.last).Seconds()*l.rate)
+	}
+	if l.last.IsZero() || t.After(l.last) {
+		l.last = t
+	}
+
+	if l.tokens < 1 {
+		l.suppressed.Add(1)
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// rateLimit reports whether the record is within the rate limit for its package.
+func (h *Handler) rateLimit(record slog.Record, res resolution) bool {
+	pkg, _ := h.recordPackage(record, res)
+	l, ok := h.rateLimiters[pkg]
+	if !ok {
+		return true
+	}
+
+	t := record.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return l.allow(record.Level, t)
+}
+
+// RateLimited returns the number of records suppressed by WithRateLimit for each rate limited package,
+// across this handler and every handler derived from the same NewHandler call.
+func (h *Handler) RateLimited() map[string]int64 {
+	counts := make(map[string]int64, len(h.rateLimiters))
+	for pkg, l := range h.rateLimiters {
+		counts[pkg] = l.suppressed.Load()
+	}
+	return counts
+}
@@ -0,0 +1,101 @@
+package slogenv_test
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestRateLimit tests that records from a rate limited package are delivered at the configured rate,
+// using the time of each record as the clock.
+func TestRateLimit(t *testing.T) {
+	t.Setenv("GO_LOG", "debug")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h, slogenv.WithRateLimit("testpackage", 5))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	burst := func(at time.Duration, level slog.Level, n int) int {
+		before := len(h.Messages())
+		for i := 0; i < n; i++ {
+			record := testpackage.Record(level, "limited")
+			record.Time = start.Add(at)
+			require.NoError(t, handler.Handle(context.Background(), record))
+		}
+		return len(h.Messages()) - before
+	}
+
+	// The bucket starts full, allowing a burst of up to the rate.
+	assert.Equal(t, 5, burst(0, slog.LevelDebug, 20))
+	// Tokens refill at the rate, and are shared between levels below error.
+	assert.Equal(t, 1, burst(200*time.Millisecond, slog.LevelInfo, 3))
+	assert.Equal(t, 2, burst(600*time.Millisecond, slog.LevelDebug, 3))
+	// Refilling stops once the bucket is full.
+	assert.Equal(t, 5, burst(10*time.Second, slog.LevelDebug, 20))
+	// Errors are never limited.
+	assert.Equal(t, 10, burst(10*time.Second, slog.LevelError, 10))
+
+	assert.Equal(t, map[string]int64{"testpackage": 15 + 2 + 1 + 15}, handler.RateLimited())
+
+	// Other packages are unaffected.
+	before := len(h.Messages())
+	logger := slog.New(handler)
+	for i := 0; i < 20; i++ {
+		logger.Debug("unlimited")
+	}
+	assert.Equal(t, 20, len(h.Messages())-before)
+}
+
+// TestRateLimitSteady tests that a steady stream faster than the rate is limited to the rate over time.
+func TestRateLimitSteady(t *testing.T) {
+	t.Setenv("GO_LOG", "debug")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h, slogenv.WithRateLimit("testpackage", 10))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 100 records per second for 10 seconds.
+	for i := 0; i < 1000; i++ {
+		record := testpackage.Record(slog.LevelDebug, "limited")
+		record.Time = start.Add(time.Duration(i) * 10 * time.Millisecond)
+		require.NoError(t, handler.Handle(context.Background(), record))
+	}
+
+	// The initial burst of 10, then 10 per second.
+	assert.InDelta(t, 10+10*10, len(h.Messages()), 1)
+}
+
+// TestRateLimitConcurrent tests that concurrent records share the same bucket.
+func TestRateLimitConcurrent(t *testing.T) {
+	t.Setenv("GO_LOG", "debug")
+
+	h := &countingHandler{}
+	handler := slogenv.NewHandler(h, slogenv.WithRateLimit("testpackage", 5))
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				record := testpackage.Record(slog.LevelDebug, "limited")
+				record.Time = at
+				_ = handler.Handle(context.Background(), record)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(5), h.debug.Load())
+	assert.Equal(t, map[string]int64{"testpackage": 795}, handler.RateLimited())
+}
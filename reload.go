@@ -0,0 +1,76 @@
+package slogenv
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// WithReloadOnSignal reloads the filter whenever the process receives sig, such as syscall.SIGHUP,
+// so verbosity can be changed without restarting. The filter is loaded the same way as when the handler
+// was created, and the new levels apply to every handler derived from it. Invalid parts of the new filter
+// are ignored, as in NewHandler. If the new levels aren't allowed, such as by WithAllowRuntimeVerbosityIncrease,
+// the previous levels are kept and a warning is written to stderr. Call Close to stop listening for the signal,
+// or see WithBaseContext.
+func WithReloadOnSignal(sig os.Signal) Opt {
+	return func(cfg *config) {
+		cfg.reloadSignal = sig
+	}
+}
+
+// reloader listens for the signal which reloads the filter.
+type reloader struct {
+	signals  chan os.Signal
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// reloadOnSignal starts reloading the filter each time the process receives sig.
+func (h *Handler) reloadOnSignal(sig os.Signal) *reloader {
+	r := &reloader{
+		signals: make(chan os.Signal, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	signal.Notify(r.signals, sig)
+
+	go func() {
+		defer close(r.stopped)
+		defer signal.Stop(r.signals)
+
+		for {
+			select {
+			case <-r.signals:
+				if err := h.reload(); err != nil {
+					fmt.Fprintf(os.Stderr, "slog-env: keeping previous levels, reloaded filter isn't allowed: %v\n", err)
+				}
+			case <-r.done:
+				return
+			case <-h.cfg.baseContext.Done():
+				return
+			}
+		}
+	}()
+	return r
+}
+
+// stop stops listening for the signal and waits for the reloader to exit. It is safe to call more than once.
+func (r *reloader) stop() {
+	r.stopOnce.Do(func() {
+		close(r.done)
+	})
+	<-r.stopped
+}
+
+// reload loads and parses the filter again, replacing the levels of this handler
+// and every handler derived from the same NewHandler call. Invalid parts of the filter are ignored,
+// and an error is only returned if the new levels aren't allowed.
+func (h *Handler) reload() error {
+	h.stateMu.Lock()
+	defer h.stateMu.Unlock()
+
+	state, _ := h.cfg.loadLevels()
+	return h.replaceLevels(state)
+}
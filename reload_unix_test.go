@@ -0,0 +1,49 @@
+//go:build unix
+
+package slogenv_test
+
+import (
+	"context"
+	"log/slog"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestReloadOnSignal tests that the filter is reloaded for the handler and derived handlers on a signal.
+func TestReloadOnSignal(t *testing.T) {
+	t.Setenv("GO_LOG", "warn")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h, slogenv.WithReloadOnSignal(syscall.SIGHUP))
+	defer handler.Close()
+
+	logger := slog.New(handler)
+	grouped := logger.WithGroup("http")
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelInfo))
+
+	t.Setenv("GO_LOG", "debug,testpackage=error,group:http=warn")
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGHUP))
+	require.Eventually(t, func() bool {
+		return logger.Enabled(context.Background(), slog.LevelDebug)
+	}, time.Second, time.Millisecond)
+
+	logger.Debug("debug")
+	testpackage.LogSomething(logger, slog.LevelWarn, "testpackage warn")
+	testpackage.LogSomething(logger, slog.LevelError, "testpackage error")
+	grouped.Info("http info")
+	grouped.Warn("http warn")
+
+	assert.Equal(t, []string{"debug", "testpackage error", "http warn"}, h.Messages())
+	assert.Equal(t, []slogenv.PackageLevel{
+		{Package: "group:http", Level: slog.LevelWarn},
+		{Package: "testpackage", Level: slog.LevelError},
+	}, handler.EffectiveLevels())
+}
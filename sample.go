@@ -0,0 +1,61 @@
+package slogenv
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// WithSampling keeps only one in n records from pkg at each level below error, to limit the volume of
+// repetitive logs from chatty packages. The first record at each level is kept, then every nth after it.
+// Errors and more severe records are never sampled out. Sampling applies after filtering, so records
+// dropped by the filter don't count towards n.
+func WithSampling(pkg string, n int) Opt {
+	return func(cfg *config) {
+		if cfg.sampling == nil {
+			cfg.sampling = make(map[string]int)
+		}
+		cfg.sampling[pkg] = n
+	}
+}
+
+// sampler counts the records from a package at each level to decide which ones to keep.
+type sampler struct {
+	n      uint64
+	counts sync.Map // slog.Level -> *atomic.Uint64
+}
+
+// newSamplers creates the samplers for each package sampled by more than one in one.
+func newSamplers(sampling map[string]int) map[string]*sampler {
+	var samplers map[string]*sampler
+	for pkg, n := range sampling {
+		if n <= 1 {
+			continue
+		}
+		if samplers == nil {
+			samplers = make(map[string]*sampler)
+		}
+		samplers[pkg] = &sampler{n: uint64(n)}
+	}
+	return samplers
+}
+
+// keep reports whether a record at level should be kept.
+func (s *sampler) keep(level slog.Level) bool {
+	if level >= slog.LevelError {
+		return true
+	}
+
+	count, ok := s.counts.Load(level)
+	if !ok {
+		count, _ = s.counts.LoadOrStore(level, &atomic.Uint64{})
+	}
+	return (count.(*atomic.Uint64).Add(1)-1)%s.n == 0
+}
+
+// sample reports whether the record should be kept by the sampler for its package.
+func (h *Handler) sample(record slog.Record, res resolution) bool {
+	pkg, _ := h.recordPackage(record, res)
+	s, ok := h.samplers[pkg]
+	return !ok || s.keep(record.Level)
+}
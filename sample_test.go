@@ -0,0 +1,91 @@
+package slogenv_test
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// countingHandler counts the records it handles at each level, and is safe for concurrent use.
+type countingHandler struct {
+	debug, info, error atomic.Int64
+}
+
+// Enabled implements slog.Handler.
+func (*countingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+// Handle implements slog.Handler.
+func (h *countingHandler) Handle(_ context.Context, record slog.Record) error {
+	switch record.Level {
+	case slog.LevelDebug:
+		h.debug.Add(1)
+	case slog.LevelInfo:
+		h.info.Add(1)
+	case slog.LevelError:
+		h.error.Add(1)
+	}
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *countingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+// WithGroup implements slog.Handler.
+func (h *countingHandler) WithGroup(string) slog.Handler { return h }
+
+// TestSampling tests keeping one in n records from a sampled package.
+func TestSampling(t *testing.T) {
+	t.Setenv("GO_LOG", "info,testpackage=debug")
+
+	h := slogenvtest.NewCaptureHandler()
+	logger := slog.New(slogenv.NewHandler(h, slogenv.WithSampling("testpackage", 3)))
+	for i := 0; i < 7; i++ {
+		testpackage.LogSomething(logger, slog.LevelDebug, "debug")
+		testpackage.LogSomething(logger, slog.LevelError, "error")
+		logger.Info("unsampled")
+	}
+	// Records dropped by the filter don't count.
+	logger.Debug("filtered")
+
+	counts := make(map[string]int)
+	for _, message := range h.Messages() {
+		counts[message]++
+	}
+	assert.Equal(t, map[string]int{"debug": 3, "error": 7, "unsampled": 7}, counts)
+	assert.Equal(t, "debug", h.Messages()[0])
+}
+
+// TestSamplingConcurrent tests that sampling keeps exactly one in n records when logging concurrently,
+// with each level sampled separately.
+func TestSamplingConcurrent(t *testing.T) {
+	t.Setenv("GO_LOG", "debug")
+
+	h := &countingHandler{}
+	logger := slog.New(slogenv.NewHandler(h, slogenv.WithSampling("testpackage", 10)))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 250; j++ {
+				testpackage.LogSomething(logger, slog.LevelDebug, "debug")
+				testpackage.LogSomething(logger, slog.LevelInfo, "info")
+				testpackage.LogSomething(logger, slog.LevelError, "error")
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(200), h.debug.Load())
+	assert.Equal(t, int64(200), h.info.Load())
+	assert.Equal(t, int64(2000), h.error.Load())
+}
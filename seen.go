@@ -0,0 +1,53 @@
+package slogenv
+
+import (
+	"log/slog"
+	"sort"
+)
+
+// SeenPackages returns the full import paths of the packages the handler has resolved records from, sorted.
+// They come from the cache of resolved call sites, so packages are only included once they have logged through
+// the handler or one derived from it at a level which depends on the package, which requires package filters
+// more verbose than the default level. Callers attributed to a package
+// outside the standard library by WithSkipStdlibFrames aren't included, since they aren't cached.
+func (h *Handler) SeenPackages() []string {
+	seen := h.seenPackages()
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// MatchSeen returns the full import paths of the seen packages, see SeenPackages, which a package filter with
+// the key pattern would apply to, sorted. Patterns have the same syntax as package keys in filters, such as
+// api, acme/api/* or acme/v?/api, so a filter can be checked against the packages actually logging before
+// it's applied.
+func (h *Handler) MatchSeen(pattern string) []string {
+	state := newLevelState(h.cfg, parsedFilter{perPackageLevel: map[string]slog.Level{pattern: 0}})
+
+	var matched []string
+	for path, resolved := range h.seenPackages() {
+		if _, _, ok := h.packageLevel(state, resolved.pkg, resolved.path); ok {
+			matched = append(matched, path)
+		}
+	}
+	sort.Strings(matched)
+	return matched
+}
+
+// seenPackages returns the resolved packages in the package cache, keyed by full import path.
+func (h *Handler) seenPackages() map[string]resolvedPackage {
+	seen := make(map[string]resolvedPackage)
+	if h.packages == nil {
+		return seen
+	}
+	h.packages.Range(func(_, value any) bool {
+		if resolved := value.(resolvedPackage); resolved.ok && resolved.path != "" {
+			seen[resolved.path] = resolved
+		}
+		return true
+	})
+	return seen
+}
@@ -0,0 +1,53 @@
+package slogenv_test
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/shadow/testpackage"
+	testpkg "github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/internal/testpackage/nested"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestMatchSeen tests listing the seen packages a filter key would match.
+func TestMatchSeen(t *testing.T) {
+	// Records are only resolved to a package if their level depends on it.
+	t.Setenv("GO_LOG", "info,db=debug")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler())
+	assert.Empty(t, handler.SeenPackages())
+
+	logger := slog.New(handler)
+	testpkg.LogSomething(logger, slog.LevelDebug, "message")
+	testpackage.LogSomething(logger.With("derived", true), slog.LevelDebug, "message")
+	nested.LogSomething(logger, slog.LevelDebug, "message")
+
+	const (
+		testpackagePath = "github.com/cbrewster/slog-env/internal/testpackage"
+		shadowPath      = "github.com/cbrewster/slog-env/internal/shadow/testpackage"
+		nestedPath      = "github.com/cbrewster/slog-env/internal/testpackage/nested"
+	)
+	assert.Equal(t, []string{shadowPath, testpackagePath, nestedPath}, handler.SeenPackages())
+
+	for _, test := range []struct {
+		pattern string
+		want    []string
+	}{
+		{pattern: "testpackage", want: []string{shadowPath, testpackagePath}},
+		// Package filters match the package name or full import path, not partial paths.
+		{pattern: "shadow/testpackage"},
+		{pattern: testpackagePath, want: []string{testpackagePath}},
+		{pattern: "testpackage/*", want: []string{nestedPath}},
+		{pattern: "internal/*", want: []string{shadowPath, testpackagePath, nestedPath}},
+		{pattern: "internal/*/testpackage", want: []string{shadowPath}},
+		{pattern: "nes?ed", want: []string{nestedPath}},
+		{pattern: "db"},
+		{pattern: "test["},
+	} {
+		assert.Equal(t, test.want, handler.MatchSeen(test.pattern), test.pattern)
+	}
+}
@@ -0,0 +1,34 @@
+package slogenvhttp
+
+import (
+	"hash/fnv"
+	"log/slog"
+	"net/http"
+
+	slogenv "github.com/cbrewster/slog-env"
+)
+
+// canaryBuckets is the number of buckets header values are hashed into.
+const canaryBuckets = 10000
+
+// Canary returns middleware which logs a fraction of requests at level, for gradually raising verbosity
+// behind a load balancer. Requests are bucketed by hashing the value of header, such as a user or session
+// ID, so the same value is always either elevated or not. Requests in the first fraction of buckets get a
+// context with slogenv.WithLevelOverride, which next must pass to its loggers. Requests without the header
+// are never elevated. A fraction of 0 elevates no requests and 1 elevates all of them.
+func Canary(header string, fraction float64, level slog.Level, next http.Handler) http.Handler {
+	threshold := uint32(max(0, min(fraction, 1)) * canaryBuckets)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if value := r.Header.Get(header); value != "" && canaryBucket(value) < threshold {
+			r = r.WithContext(slogenv.WithLevelOverride(r.Context(), level))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// canaryBucket returns the bucket for a header value.
+func canaryBucket(value string) uint32 {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(value))
+	return hash.Sum32() % canaryBuckets
+}
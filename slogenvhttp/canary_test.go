@@ -0,0 +1,64 @@
+package slogenvhttp_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/slogenvhttp"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestCanary tests that a consistent fraction of requests, bucketed by header, is logged at the canary level.
+func TestCanary(t *testing.T) {
+	t.Setenv("GO_LOG", "info")
+
+	capture := slogenvtest.NewCaptureHandler()
+	logger := slog.New(slogenv.NewHandler(capture))
+	next := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		logger.DebugContext(r.Context(), r.Header.Get("X-User"))
+	})
+
+	serve := func(handler http.Handler, user string) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if user != "" {
+			r.Header.Set("X-User", user)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	for _, fraction := range []float64{0, 0.1, 0.5, 1} {
+		capture.Reset()
+		handler := slogenvhttp.Canary("X-User", fraction, slog.LevelDebug, next)
+		for i := 0; i < 2000; i++ {
+			serve(handler, "user-"+strconv.Itoa(i))
+		}
+		assert.InDelta(t, fraction, float64(len(capture.Messages()))/2000, 0.05, "fraction %v", fraction)
+	}
+
+	// The same header value is always in the same bucket.
+	capture.Reset()
+	handler := slogenvhttp.Canary("X-User", 0.5, slog.LevelDebug, next)
+	for j := 0; j < 10; j++ {
+		for i := 0; i < 10; i++ {
+			serve(handler, "user-"+strconv.Itoa(i))
+		}
+	}
+	counts := make(map[string]int)
+	for _, msg := range capture.Messages() {
+		counts[msg]++
+	}
+	for user, count := range counts {
+		assert.Equal(t, 10, count, user)
+	}
+
+	// Requests without the header aren't elevated.
+	capture.Reset()
+	serve(slogenvhttp.Canary("X-User", 1, slog.LevelDebug, next), "")
+	assert.Empty(t, capture.Messages())
+}
@@ -0,0 +1,102 @@
+// Package slogenvhttp provides an HTTP handler for viewing and changing slog-env levels at runtime.
+package slogenvhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	slogenv "github.com/cbrewster/slog-env"
+)
+
+// Levels is the JSON representation of a handler's levels.
+// Levels are written in filter syntax, such as debug or warn+2, and off for silenced packages.
+type Levels struct {
+	// Default is the level for packages without a filter.
+	Default string `json:"default,omitempty"`
+	// Packages is the level for each package and group filter.
+	// When updating, an empty level removes the package's filter.
+	Packages map[string]string `json:"packages,omitempty"`
+}
+
+// Handler returns an HTTP handler which serves the levels of h as JSON on GET, and updates them on PUT or POST.
+// Updates only change the default level and packages they include, and respond with the resulting levels.
+// If any level or package pattern in an update is invalid, or the handler doesn't allow the update, nothing
+// is changed and the response is a 400.
+//
+// Mount it behind authentication, since anyone who can reach it can change how much the application logs.
+func Handler(h *slogenv.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			if err := update(h, r); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(current(h))
+	})
+}
+
+// current returns the levels h is using.
+func current(h *slogenv.Handler) Levels {
+	levels := Levels{
+		Default:  formatLevel(h.DefaultLevel()),
+		Packages: make(map[string]string),
+	}
+	for pkg, level := range h.PackageLevels() {
+		levels.Packages[pkg] = formatLevel(level)
+	}
+	return levels
+}
+
+// update applies the levels in the request body to h as a single update, after checking they are all valid.
+func update(h *slogenv.Handler, r *http.Request) error {
+	var body Levels
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return fmt.Errorf("invalid body: %w", err)
+	}
+
+	var defaultLevel *slog.Level
+	if body.Default != "" {
+		level, err := h.ParseLevel(body.Default)
+		if err != nil {
+			return err
+		}
+		defaultLevel = &level
+	}
+
+	packages := make(map[string]*slog.Level, len(body.Packages))
+	for pkg, s := range body.Packages {
+		if pkg == "" {
+			return fmt.Errorf("empty package name")
+		}
+		if s == "" {
+			packages[pkg] = nil
+			continue
+		}
+		level, err := h.ParseLevel(s)
+		if err != nil {
+			return fmt.Errorf("%w for package %q", err, pkg)
+		}
+		packages[pkg] = &level
+	}
+
+	return h.UpdateLevels(defaultLevel, packages)
+}
+
+// formatLevel formats a level in filter syntax.
+func formatLevel(level slog.Level) string {
+	if level == slogenv.LevelOff {
+		return "off"
+	}
+	return level.String()
+}
@@ -0,0 +1,104 @@
+package slogenvhttp_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/slogenvhttp"
+)
+
+// do sends a request to the handler and returns the response.
+func do(t *testing.T, handler http.Handler, method, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(method, "/debug/loglevels", strings.NewReader(body)))
+	return w
+}
+
+// decode decodes the levels in a response.
+func decode(t *testing.T, w *httptest.ResponseRecorder) slogenvhttp.Levels {
+	t.Helper()
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var levels slogenvhttp.Levels
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&levels))
+	return levels
+}
+
+// TestHandler tests viewing and changing levels over HTTP.
+func TestHandler(t *testing.T) {
+	t.Setenv("GO_LOG", "info,db=warn,cache=off")
+
+	h := slogenv.NewHandler(slog.NewTextHandler(nil, nil))
+	server := slogenvhttp.Handler(h)
+
+	assert.Equal(t, slogenvhttp.Levels{
+		Default:  "INFO",
+		Packages: map[string]string{"db": "WARN", "cache": "off"},
+	}, decode(t, do(t, server, http.MethodGet, "")))
+
+	levels := decode(t, do(t, server, http.MethodPut, `{"default":"debug","packages":{"acme/api":"error","db":"","cache":"info+2"}}`))
+	assert.Equal(t, slogenvhttp.Levels{
+		Default:  "DEBUG",
+		Packages: map[string]string{"acme/api": "ERROR", "cache": "INFO+2"},
+	}, levels)
+	assert.Equal(t, slog.LevelDebug, h.DefaultLevel())
+	assert.Equal(t, map[string]slog.Level{"acme/api": slog.LevelError, "cache": slog.LevelInfo + 2}, h.PackageLevels())
+
+	// Leaving out the default keeps it unchanged.
+	levels = decode(t, do(t, server, http.MethodPost, `{"packages":{"db":"warn"}}`))
+	assert.Equal(t, "DEBUG", levels.Default)
+	assert.Equal(t, slog.LevelWarn, h.PackageLevels()["db"])
+}
+
+// TestHandlerInvalid tests that invalid updates are rejected without changing any levels.
+func TestHandlerInvalid(t *testing.T) {
+	t.Setenv("GO_LOG", "info,db=warn")
+
+	h := slogenv.NewHandler(slog.NewTextHandler(nil, nil))
+	server := slogenvhttp.Handler(h)
+
+	for _, body := range []string{
+		`{"default":"loud"}`,
+		`{"default":"debug","packages":{"db":"debug","cache":"sometimes"}}`,
+		`{"packages":{"":"debug"}}`,
+		`{"default":"debug","packages":{"a[":"debug"}}`,
+		`not json`,
+	} {
+		t.Run(body, func(t *testing.T) {
+			w := do(t, server, http.MethodPut, body)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, slog.LevelInfo, h.DefaultLevel())
+			assert.Equal(t, map[string]slog.Level{"db": slog.LevelWarn}, h.PackageLevels())
+		})
+	}
+
+	w := do(t, server, http.MethodDelete, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, PUT, POST", w.Header().Get("Allow"))
+}
+
+// TestHandlerNotAllowed tests that updates the handler doesn't allow are rejected.
+func TestHandlerNotAllowed(t *testing.T) {
+	t.Setenv("GO_LOG", "info")
+
+	h := slogenv.NewHandler(slog.NewTextHandler(nil, nil), slogenv.WithAllowRuntimeVerbosityIncrease(false))
+	server := slogenvhttp.Handler(h)
+
+	w := do(t, server, http.MethodPut, `{"default":"debug"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, slog.LevelInfo, h.DefaultLevel())
+
+	assert.Equal(t, "WARN", decode(t, do(t, server, http.MethodPut, `{"default":"warn"}`)).Default)
+}
@@ -0,0 +1,133 @@
+package slogenvtest
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// CaptureHandler is a slog.Handler which stores every record it handles, so tests can assert on
+// the records slog-env lets through. It is enabled at every level, and is safe for concurrent use.
+// Handlers derived from it with WithAttrs and WithGroup store records in the same place, with their
+// attributes and groups added to each record, as a JSON or text handler would output them.
+//
+// A typical test wraps it with slog-env:
+//
+//	capture := slogenvtest.NewCaptureHandler()
+//	logger := slog.New(slogenv.NewHandler(capture))
+//	logger.Debug("dropped")
+//	logger.Info("kept")
+//	// capture.Messages() is []string{"kept"}
+type CaptureHandler struct {
+	store *captureStore
+	// ops are the WithAttrs and WithGroup calls the handler was derived with, in order.
+	ops []captureOp
+}
+
+// captureStore holds the records captured by a handler and the handlers derived from it.
+type captureStore struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+// captureOp is a call to WithGroup, if group is set, or WithAttrs otherwise.
+type captureOp struct {
+	group string
+	attrs []slog.Attr
+}
+
+// NewCaptureHandler creates a handler which captures every record.
+func NewCaptureHandler() *CaptureHandler {
+	return &CaptureHandler{store: &captureStore{}}
+}
+
+// Enabled implements slog.Handler.
+func (*CaptureHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle implements slog.Handler.
+func (h *CaptureHandler) Handle(_ context.Context, record slog.Record) error {
+	if len(h.ops) > 0 {
+		record = h.withOps(record)
+	} else {
+		record = record.Clone()
+	}
+
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	h.store.records = append(h.store.records, record)
+	return nil
+}
+
+// withOps returns a copy of record with the attributes and groups from WithAttrs and WithGroup applied.
+func (h *CaptureHandler) withOps(record slog.Record) slog.Record {
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	for i := len(h.ops) - 1; i >= 0; i-- {
+		op := h.ops[i]
+		switch {
+		case op.group == "":
+			attrs = append(op.attrs[:len(op.attrs):len(op.attrs)], attrs...)
+		case len(attrs) > 0:
+			// Like slog's own handlers, groups without attributes are left out.
+			attrs = []slog.Attr{{Key: op.group, Value: slog.GroupValue(attrs...)}}
+		}
+	}
+
+	captured := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	captured.AddAttrs(attrs...)
+	return captured
+}
+
+// WithAttrs implements slog.Handler.
+func (h *CaptureHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(captureOp{attrs: attrs})
+}
+
+// WithGroup implements slog.Handler.
+func (h *CaptureHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(captureOp{group: name})
+}
+
+// with returns a handler derived from h with op applied.
+func (h *CaptureHandler) with(op captureOp) *CaptureHandler {
+	return &CaptureHandler{store: h.store, ops: append(h.ops[:len(h.ops):len(h.ops)], op)}
+}
+
+// Records returns a copy of the records captured so far, in the order they were handled.
+func (h *CaptureHandler) Records() []slog.Record {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	return append([]slog.Record(nil), h.store.records...)
+}
+
+// Messages returns the messages of the records captured so far, in the order they were handled.
+// It returns nil if no records were captured.
+func (h *CaptureHandler) Messages() []string {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+
+	var messages []string
+	for _, record := range h.store.records {
+		messages = append(messages, record.Message)
+	}
+	return messages
+}
+
+// Reset discards the records captured so far.
+func (h *CaptureHandler) Reset() {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	h.store.records = nil
+}
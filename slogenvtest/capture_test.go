@@ -0,0 +1,136 @@
+package slogenvtest_test
+
+import (
+	"log/slog"
+	"sync"
+	"testing"
+	"testing/slogtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestCaptureHandler tests capturing the records let through by slog-env.
+func TestCaptureHandler(t *testing.T) {
+	t.Setenv("GO_LOG", "info")
+
+	capture := slogenvtest.NewCaptureHandler()
+	logger := slog.New(slogenv.NewHandler(capture))
+	logger.Debug("debug")
+	logger.Info("info", "key", "value")
+	logger.Warn("warn")
+
+	assert.Equal(t, []string{"info", "warn"}, capture.Messages())
+
+	records := capture.Records()
+	require.Len(t, records, 2)
+	assert.Equal(t, slog.LevelInfo, records[0].Level)
+	assert.Equal(t, slog.LevelWarn, records[1].Level)
+	assert.Equal(t, map[string]any{"key": "value"}, attrMap(records[0]))
+
+	capture.Reset()
+	assert.Nil(t, capture.Messages())
+	assert.Empty(t, capture.Records())
+}
+
+// TestCaptureHandlerDerived tests that derived handlers share the captured records and add their
+// attributes and groups to them.
+func TestCaptureHandlerDerived(t *testing.T) {
+	capture := slogenvtest.NewCaptureHandler()
+	logger := slog.New(capture).With("request", "abc").WithGroup("http").With("method", "GET").WithGroup("empty")
+
+	logger.Info("grouped", "status", 200)
+	logger.Info("empty group")
+	slog.New(capture).Info("root")
+
+	records := capture.Records()
+	require.Len(t, records, 3)
+	assert.Equal(t, map[string]any{
+		"request": "abc",
+		"http": map[string]any{
+			"method": "GET",
+			"empty":  map[string]any{"status": int64(200)},
+		},
+	}, attrMap(records[0]))
+	assert.Equal(t, map[string]any{
+		"request": "abc",
+		"http":    map[string]any{"method": "GET"},
+	}, attrMap(records[1]))
+	assert.Empty(t, attrMap(records[2]))
+}
+
+// TestCaptureHandlerConcurrent tests capturing records from several goroutines.
+func TestCaptureHandlerConcurrent(t *testing.T) {
+	capture := slogenvtest.NewCaptureHandler()
+	logger := slog.New(capture)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.With("j", j).Info("message")
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, capture.Messages(), 800)
+}
+
+// TestCaptureHandlerConformance tests that the handler follows the slog.Handler contract.
+func TestCaptureHandlerConformance(t *testing.T) {
+	capture := slogenvtest.NewCaptureHandler()
+	err := slogtest.TestHandler(capture, func() []map[string]any {
+		var results []map[string]any
+		for _, record := range capture.Records() {
+			result := attrMap(record)
+			result[slog.LevelKey] = record.Level
+			result[slog.MessageKey] = record.Message
+			if !record.Time.IsZero() {
+				result[slog.TimeKey] = record.Time
+			}
+			results = append(results, result)
+		}
+		return results
+	})
+	require.NoError(t, err)
+}
+
+// attrMap returns the attributes of a record as a map, with groups as nested maps.
+func attrMap(record slog.Record) map[string]any {
+	m := make(map[string]any)
+	record.Attrs(func(attr slog.Attr) bool {
+		addAttr(m, attr)
+		return true
+	})
+	return m
+}
+
+// addAttr adds an attribute to m, resolving its value and inlining groups with empty keys.
+func addAttr(m map[string]any, attr slog.Attr) {
+	value := attr.Value.Resolve()
+	if value.Kind() != slog.KindGroup {
+		if attr.Key != "" {
+			m[attr.Key] = value.Any()
+		}
+		return
+	}
+
+	group := value.Group()
+	if len(group) == 0 {
+		return
+	}
+	target := m
+	if attr.Key != "" {
+		target = make(map[string]any)
+		m[attr.Key] = target
+	}
+	for _, a := range group {
+		addAttr(target, a)
+	}
+}
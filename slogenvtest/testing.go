@@ -0,0 +1,51 @@
+// Package slogenvtest provides helpers for using slog-env in tests.
+package slogenvtest
+
+import (
+	"bytes"
+	"log/slog"
+	"sync"
+	"testing"
+
+	slogenv "github.com/cbrewster/slog-env"
+)
+
+// NewTestingHandler creates an env logger handler which writes logs to t.Log, so they are shown
+// alongside the test and respect -v. The usual slog-env filtering applies, so GO_LOG can be set per test.
+// Logs written after the test completes are discarded instead of panicking.
+func NewTestingHandler(t testing.TB, opts ...slogenv.Opt) *slogenv.Handler {
+	w := &testingWriter{t: t}
+	t.Cleanup(w.done)
+
+	inner := slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug - 8})
+	return slogenv.NewHandler(inner, opts...)
+}
+
+// testingWriter writes each line to t.Log until the test completes.
+type testingWriter struct {
+	mu       sync.Mutex
+	t        testing.TB
+	finished bool
+}
+
+// Write implements io.Writer.
+func (w *testingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.finished {
+		return len(p), nil
+	}
+
+	w.t.Helper()
+	w.t.Log(string(bytes.TrimSuffix(p, []byte("\n"))))
+	return len(p), nil
+}
+
+// done stops writing to t.Log.
+func (w *testingWriter) done() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.finished = true
+}
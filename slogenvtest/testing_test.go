@@ -0,0 +1,75 @@
+package slogenvtest_test
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// fakeT records the output and cleanups of a test.
+type fakeT struct {
+	testing.TB
+	logs     []string
+	cleanups []func()
+}
+
+// Helper implements testing.TB.
+func (t *fakeT) Helper() {}
+
+// Log implements testing.TB.
+func (t *fakeT) Log(args ...any) {
+	t.logs = append(t.logs, fmt.Sprint(args...))
+}
+
+// Cleanup implements testing.TB.
+func (t *fakeT) Cleanup(f func()) {
+	t.cleanups = append(t.cleanups, f)
+}
+
+// finish runs the cleanups, as happens when a test completes.
+func (t *fakeT) finish() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+// TestNewTestingHandler tests that logs are routed to t.Log and filtered.
+func TestNewTestingHandler(t *testing.T) {
+	os.Setenv("GO_LOG", "warn")
+	defer os.Unsetenv("GO_LOG")
+
+	ft := &fakeT{}
+	logger := slog.New(slogenvtest.NewTestingHandler(ft))
+	logger.Info("info")
+	logger.Warn("warn", "key", "value")
+
+	require.Len(t, ft.logs, 1)
+	assert.Contains(t, ft.logs[0], "level=WARN")
+	assert.Contains(t, ft.logs[0], "msg=warn")
+	assert.Contains(t, ft.logs[0], "key=value")
+	assert.NotContains(t, ft.logs[0], "\n")
+}
+
+// TestNewTestingHandlerAfterTest tests that logging after the test completes is discarded.
+func TestNewTestingHandlerAfterTest(t *testing.T) {
+	ft := &fakeT{}
+	logger := slog.New(slogenvtest.NewTestingHandler(ft))
+	logger.Info("during")
+	ft.finish()
+	logger.Info("after")
+
+	require.Len(t, ft.logs, 1)
+	assert.Contains(t, ft.logs[0], "msg=during")
+}
+
+// TestNewTestingHandlerRealTest tests the handler with a real test.
+func TestNewTestingHandlerRealTest(t *testing.T) {
+	logger := slog.New(slogenvtest.NewTestingHandler(t))
+	logger.Info("logged through t.Log")
+}
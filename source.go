@@ -0,0 +1,66 @@
+package slogenv
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// WithFilterAnnotationFile reads the default filter from the annotation key in a file of key="value" lines,
+// such as the annotations file mounted by the Kubernetes downward API. The environment variable still
+// takes precedence. If the file can't be read or doesn't contain key, the filter from WithFilterFS or
+// WithDefaultFilter is used and NewHandlerWithError reports the problem.
+func WithFilterAnnotationFile(path, key string) Opt {
+	return func(cfg *config) {
+		cfg.annotationFilterLoader = func() (string, error) {
+			return readAnnotation(path, key)
+		}
+	}
+}
+
+// WithFilterFS reads the default filter from the file name in fsys, such as an [embed.FS] compiled into the binary.
+// The environment variable and WithFilterAnnotationFile still take precedence. If the file can't be read,
+// the filter from WithDefaultFilter is used and NewHandlerWithError reports the problem.
+func WithFilterFS(fsys fs.FS, name string) Opt {
+	return func(cfg *config) {
+		cfg.fsFilterLoader = func() (string, error) {
+			data, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSpace(string(data)), nil
+		}
+	}
+}
+
+// readAnnotation returns the value of key from a file of key="value" lines.
+// Values may be quoted with Go string syntax, as written by the downward API, or unquoted.
+func readAnnotation(path, key string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), "=")
+		if !ok || strings.TrimSpace(k) != key {
+			continue
+		}
+
+		v = strings.TrimSpace(v)
+		if strings.HasPrefix(v, `"`) {
+			return strconv.Unquote(v)
+		}
+		return v, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	return "", fmt.Errorf("annotation %q not found in %s", key, path)
+}
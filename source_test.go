@@ -0,0 +1,164 @@
+package slogenv_test
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestFilterAnnotationFile tests reading the filter from a downward API annotations file.
+func TestFilterAnnotationFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "annotations")
+	require.NoError(t, os.WriteFile(path, []byte(`kubernetes.io/config.seen="2024-01-01T00:00:00Z"
+example.com/log-level="warn,testpackage=debug"
+example.com/owner=platform
+`), 0o644))
+
+	for _, test := range []struct {
+		name         string
+		env          string
+		key          string
+		wantMessages []string
+	}{
+		{
+			name:         "annotation",
+			key:          "example.com/log-level",
+			wantMessages: []string{"warn", "error", "testpackage debug"},
+		},
+		{
+			name:         "missing annotation",
+			key:          "example.com/missing",
+			wantMessages: []string{"error"},
+		},
+		{
+			name:         "env var wins",
+			env:          "info",
+			key:          "example.com/log-level",
+			wantMessages: []string{"info", "warn", "error"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if test.env != "" {
+				os.Setenv("GO_LOG", test.env)
+				defer os.Unsetenv("GO_LOG")
+			}
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h,
+				slogenv.WithDefaultFilter("error"),
+				slogenv.WithFilterAnnotationFile(path, test.key),
+			))
+			logger.Info("info")
+			logger.Warn("warn")
+			logger.Error("error")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
+
+// TestFilterFS tests reading the filter from a file system.
+func TestFilterFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config/log-filter": &fstest.MapFile{Data: []byte("warn,testpackage=debug\n")},
+	}
+
+	for _, test := range []struct {
+		name         string
+		env          string
+		file         string
+		wantMessages []string
+	}{
+		{
+			name:         "file",
+			file:         "config/log-filter",
+			wantMessages: []string{"warn", "error", "testpackage debug"},
+		},
+		{
+			name:         "missing file",
+			file:         "config/missing",
+			wantMessages: []string{"error"},
+		},
+		{
+			name:         "env var wins",
+			env:          "info",
+			file:         "config/log-filter",
+			wantMessages: []string{"info", "warn", "error"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if test.env != "" {
+				os.Setenv("GO_LOG", test.env)
+				defer os.Unsetenv("GO_LOG")
+			}
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h,
+				slogenv.WithDefaultFilter("error"),
+				slogenv.WithFilterFS(fsys, test.file),
+			))
+			logger.Info("info")
+			logger.Warn("warn")
+			logger.Error("error")
+			testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
+
+// TestFilterAnnotationFileAndFS tests that the annotation file takes precedence over a file system whichever
+// order the options are passed in, falling back to the file system if the annotation is missing.
+func TestFilterAnnotationFileAndFS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "annotations")
+	require.NoError(t, os.WriteFile(path, []byte(`example.com/log-level="warn"`+"\n"), 0o644))
+	fsys := fstest.MapFS{
+		"log-filter": &fstest.MapFile{Data: []byte("debug\n")},
+	}
+
+	for _, test := range []struct {
+		name        string
+		opts        []slogenv.Opt
+		wantDefault slog.Level
+		wantErr     bool
+	}{
+		{
+			name:        "annotation first",
+			opts:        []slogenv.Opt{slogenv.WithFilterAnnotationFile(path, "example.com/log-level"), slogenv.WithFilterFS(fsys, "log-filter")},
+			wantDefault: slog.LevelWarn,
+		},
+		{
+			name:        "file system first",
+			opts:        []slogenv.Opt{slogenv.WithFilterFS(fsys, "log-filter"), slogenv.WithFilterAnnotationFile(path, "example.com/log-level")},
+			wantDefault: slog.LevelWarn,
+		},
+		{
+			name:        "missing annotation",
+			opts:        []slogenv.Opt{slogenv.WithFilterAnnotationFile(path, "example.com/missing"), slogenv.WithFilterFS(fsys, "log-filter")},
+			wantDefault: slog.LevelDebug,
+			wantErr:     true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("GO_LOG", "")
+
+			handler, err := slogenv.NewHandlerWithError(slogenvtest.NewCaptureHandler(), test.opts...)
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.wantDefault, handler.DefaultLevel())
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package slogenv
+
+import "context"
+
+// SpanRuleAttributeKey is the span attribute recording which filter rule caused a record to be emitted.
+const SpanRuleAttributeKey = "slogenv.matched_rule"
+
+// SpanAttributeSetter is a tracing span which can record a string attribute.
+// An adapter around an OpenTelemetry trace.Span satisfies this with a single SetAttributes call,
+// which keeps slog-env free of a tracing dependency.
+type SpanAttributeSetter interface {
+	SetAttribute(key, value string)
+}
+
+// WithMatchedRuleSpanAttribute records the filter rule which caused a record to be emitted on the active span,
+// under the [SpanRuleAttributeKey] attribute. spanFromContext returns the span for a record's context, or nil if there is none.
+// The attribute is only set when a package filter, rather than the default level, let the record through.
+// Setting the attribute is idempotent, so a span logging several records for the same rule records it once.
+func WithMatchedRuleSpanAttribute(spanFromContext func(context.Context) SpanAttributeSetter) Opt {
+	return func(cfg *config) {
+		cfg.spanFromContext = spanFromContext
+	}
+}
@@ -0,0 +1,61 @@
+package slogenv_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// fakeSpan records the attributes set on it.
+type fakeSpan struct {
+	attributes map[string]string
+}
+
+// SetAttribute implements slogenv.SpanAttributeSetter.
+func (s *fakeSpan) SetAttribute(key, value string) {
+	s.attributes[key] = value
+}
+
+type spanKey struct{}
+
+// spanFromContext returns the fake span stored in ctx, if any.
+func spanFromContext(ctx context.Context) slogenv.SpanAttributeSetter {
+	if span, ok := ctx.Value(spanKey{}).(*fakeSpan); ok {
+		return span
+	}
+	return nil
+}
+
+// TestMatchedRuleSpanAttribute tests that the matched rule is recorded on the active span.
+func TestMatchedRuleSpanAttribute(t *testing.T) {
+	os.Setenv("GO_LOG", "warn,testpackage=debug")
+	defer os.Unsetenv("GO_LOG")
+
+	h := slogenvtest.NewCaptureHandler()
+	logger := slog.New(slogenv.NewHandler(h, slogenv.WithMatchedRuleSpanAttribute(spanFromContext)))
+
+	// Records emitted because of the default level don't set the attribute.
+	defaultSpan := &fakeSpan{attributes: map[string]string{}}
+	logger.WarnContext(context.WithValue(context.Background(), spanKey{}, defaultSpan), "warn")
+	assert.Empty(t, defaultSpan.attributes)
+
+	// Records emitted because of a package rule do.
+	ruleSpan := &fakeSpan{attributes: map[string]string{}}
+	ctx := context.WithValue(context.Background(), spanKey{}, ruleSpan)
+	testpackage.LogContext(ctx, logger, slog.LevelDebug, "testpackage debug")
+	assert.Equal(t, map[string]string{slogenv.SpanRuleAttributeKey: "testpackage=DEBUG"}, ruleSpan.attributes)
+
+	// Dropped records don't set the attribute.
+	droppedSpan := &fakeSpan{attributes: map[string]string{}}
+	logger.DebugContext(context.WithValue(context.Background(), spanKey{}, droppedSpan), "dropped")
+	assert.Empty(t, droppedSpan.attributes)
+
+	assert.Equal(t, []string{"warn", "testpackage debug"}, h.Messages())
+}
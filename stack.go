@@ -0,0 +1,104 @@
+package slogenv
+
+import (
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// maxStackDepth is the number of frames searched when walking the stack.
+const maxStackDepth = 64
+
+// packagePath returns the full import path of the package from a formatted function name.
+// Example:
+// github.com/cbrewster/slog-env_test.TestFilterPackage
+// Will return github.com/cbrewster/slog-env_test
+//
+// The package path ends at the first dot after its last slash. Slashes in the type arguments of generic
+// functions, which the runtime reports as [...] but other formats spell out, such as pkg.Map[a/b.T],
+// are ignored. The runtime escapes dots in the last element of the path, as in gopkg.in/yaml%2ev3.Marshal,
+// so escaped characters are unescaped.
+func packagePath(function string) (string, bool) {
+	name := function
+	if bracket := strings.IndexByte(name, '['); bracket >= 0 {
+		name = name[:bracket]
+	}
+	lastSlash := strings.LastIndexByte(name, '/')
+	dot := strings.IndexByte(name[lastSlash+1:], '.')
+	if dot < 0 {
+		return "", false
+	}
+
+	path := name[:lastSlash+1+dot]
+	if strings.IndexByte(path, '%') >= 0 {
+		if unescaped, err := url.PathUnescape(path); err == nil {
+			path = unescaped
+		}
+	}
+	return path, true
+}
+
+// isStdlibFunction reports whether the function belongs to a standard library package,
+// using the heuristic that standard library import paths have no dot in their first element.
+func isStdlibFunction(function string) bool {
+	path, ok := packagePath(function)
+	if !ok || path == "main" {
+		return false
+	}
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// callerFrame returns the frame for the return address pc, like runtime.CallersFrames does for a single pc,
+// but without allocating. If the call was inlined, FuncForPC reports the innermost function, which is the same
+// function CallersFrames reports first. Like CallersFrames, it looks up pc-1 so the return address is attributed
+// to the call instruction rather than the instruction following it.
+func callerFrame(pc uintptr) runtime.Frame {
+	if pc == 0 {
+		return runtime.Frame{}
+	}
+	fn := runtime.FuncForPC(pc - 1)
+	if fn == nil {
+		return runtime.Frame{}
+	}
+	file, line := fn.FileLine(pc - 1)
+	return runtime.Frame{PC: pc - 1, Func: fn, Function: fn.Name(), File: file, Line: line, Entry: fn.Entry()}
+}
+
+// stackPool holds the buffers stacks are walked with, since callers outside the standard library can't be
+// cached and are found again for every record.
+var stackPool = sync.Pool{
+	New: func() any {
+		return new([maxStackDepth]uintptr)
+	},
+}
+
+// callerSkippingStdlib finds pc on the current stack and returns the first frame
+// at or above it which does not belong to the standard library.
+func callerSkippingStdlib(pc uintptr) (runtime.Frame, bool) {
+	buf := stackPool.Get().(*[maxStackDepth]uintptr)
+	defer stackPool.Put(buf)
+
+	pcs := buf[:runtime.Callers(1, buf[:])]
+	for i, callerPC := range pcs {
+		if callerPC == pc {
+			return firstNonStdlibFrame(runtime.CallersFrames(pcs[i:]).Next)
+		}
+	}
+
+	return runtime.Frame{}, false
+}
+
+// firstNonStdlibFrame returns the first frame produced by next which does not belong to the standard library.
+func firstNonStdlibFrame(next func() (runtime.Frame, bool)) (runtime.Frame, bool) {
+	for {
+		f, more := next()
+		if f.Function != "" && !isStdlibFunction(f.Function) {
+			return f, true
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
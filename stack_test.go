@@ -0,0 +1,175 @@
+package slogenv
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cbrewster/slog-env/internal/testpackage"
+)
+
+// syntheticFrames returns an iterator over frames for the given function names, like runtime.Frames.Next.
+func syntheticFrames(functions ...string) func() (runtime.Frame, bool) {
+	return func() (runtime.Frame, bool) {
+		if len(functions) == 0 {
+			return runtime.Frame{}, false
+		}
+		f := runtime.Frame{Function: functions[0]}
+		functions = functions[1:]
+		return f, len(functions) > 0
+	}
+}
+
+// TestIsStdlibFunction tests the standard library heuristic.
+func TestIsStdlibFunction(t *testing.T) {
+	for function, want := range map[string]bool{
+		"log.(*Logger).output":                         true,
+		"log/slog.(*Logger).Info":                      true,
+		"net/http.(*Server).logf":                      true,
+		"main.main":                                    false,
+		"github.com/acme/app/db.Query":                 false,
+		"github.com/cbrewster/slog-env_test.TestThing": false,
+		"": false,
+	} {
+		assert.Equal(t, want, isStdlibFunction(function), function)
+	}
+}
+
+// TestParsePackage tests parsing the package and its import path out of each shape of function name.
+func TestParsePackage(t *testing.T) {
+	for _, test := range []struct {
+		function string
+		wantPkg  string
+		wantPath string
+	}{
+		{function: "github.com/acme/app/db.Query", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "github.com/acme/app/db.(*Conn).Query", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "github.com/acme/app/db.Conn.Query", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "github.com/acme/app/db.(*Conn).Query-fm", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "github.com/acme/app/db.Query.func1", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "github.com/acme/app/db.Query.func1.2", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "github.com/acme/app/db.(*Conn).Query.func1", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "github.com/acme/app/db.init", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "github.com/acme/app/db.init.0", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "github.com/acme/app/db.init.func1", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "github.com/acme/app/db.glob..func1", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "github.com/acme/app/db.Map[...]", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "github.com/acme/app/db.Map[...].func1", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "github.com/acme/app/db.(*List[...]).Push", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "github.com/acme/app/db.Map[github.com/acme/app/model.User]", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "github.com/acme/app/db.(*List[net/http.Header]).Push", wantPkg: "db", wantPath: "github.com/acme/app/db"},
+		{function: "gopkg.in/yaml%2ev3.Marshal", wantPkg: "yaml.v3", wantPath: "gopkg.in/yaml.v3"},
+		{function: "gopkg.in/yaml%2ev3.(*decoder).unmarshal.func1", wantPkg: "yaml.v3", wantPath: "gopkg.in/yaml.v3"},
+		{function: "github.com/cbrewster/slog-env_test.TestFilterPackage", wantPkg: "slog-env_test", wantPath: "github.com/cbrewster/slog-env_test"},
+		{function: "log/slog.(*Logger).Info", wantPkg: "slog", wantPath: "log/slog"},
+		{function: "main.main", wantPkg: "main", wantPath: "main"},
+		{function: "main.main.func1", wantPkg: "main", wantPath: "main"},
+		{function: "main"},
+		{function: "github.com/acme/app/db"},
+		{function: ""},
+	} {
+		pkg, ok := parsePackage(test.function)
+		assert.Equal(t, test.wantPkg != "", ok, test.function)
+		assert.Equal(t, test.wantPkg, pkg, test.function)
+
+		path, ok := packagePath(test.function)
+		assert.Equal(t, test.wantPath != "", ok, test.function)
+		assert.Equal(t, test.wantPath, path, test.function)
+	}
+}
+
+// genericCallerFunction returns the function name the runtime reports for a closure in a generic function.
+func genericCallerFunction[T any]() string {
+	return func() string {
+		var pcs [1]uintptr
+		runtime.Callers(1, pcs[:])
+		return callerFrame(pcs[0]).Function
+	}()
+}
+
+// TestParsePackageRuntime tests parsing the package out of function names produced by the runtime.
+func TestParsePackageRuntime(t *testing.T) {
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	closure := func() string {
+		runtime.Callers(1, pcs[:])
+		return callerFrame(pcs[0]).Function
+	}
+
+	for _, function := range []string{
+		callerFrame(pcs[0]).Function,
+		closure(),
+		genericCallerFunction[map[string]context.Context](),
+	} {
+		pkg, ok := parsePackage(function)
+		assert.True(t, ok, function)
+		assert.Equal(t, "slog-env", pkg, function)
+
+		path, ok := packagePath(function)
+		assert.True(t, ok, function)
+		assert.Equal(t, "github.com/cbrewster/slog-env", path, function)
+	}
+}
+
+// TestFirstNonStdlibFrame tests finding the first user frame in a synthesized stack.
+func TestFirstNonStdlibFrame(t *testing.T) {
+	f, ok := firstNonStdlibFrame(syntheticFrames(
+		"log.(*Logger).output",
+		"log.Printf",
+		"github.com/acme/app/db.Query",
+		"main.main",
+	))
+	assert.True(t, ok)
+	assert.Equal(t, "github.com/acme/app/db.Query", f.Function)
+
+	pkg, ok := parsePackage(f.Function)
+	assert.True(t, ok)
+	assert.Equal(t, "db", pkg)
+
+	_, ok = firstNonStdlibFrame(syntheticFrames("log.Printf", "runtime.goexit"))
+	assert.False(t, ok)
+}
+
+// pcHandler records the program counter of every record.
+type pcHandler struct {
+	pcs []uintptr
+}
+
+func (*pcHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *pcHandler) Handle(_ context.Context, record slog.Record) error {
+	h.pcs = append(h.pcs, record.PC)
+	return nil
+}
+func (h *pcHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *pcHandler) WithGroup(string) slog.Handler      { return h }
+
+// TestCallerFunction tests that FuncForPC resolves the same function and file as runtime.CallersFrames,
+// including for calls inlined into another package. testpackage.LogSomething is small enough to be inlined
+// into this test, so its records have a program counter in this function, while
+// testpackage.LogSomethingNoInline never is.
+func TestCallerFunction(t *testing.T) {
+	h := pcHandler{}
+	logger := slog.New(&h)
+	testpackage.LogSomething(logger, slog.LevelInfo, "inlined")
+	testpackage.LogSomethingNoInline(logger, slog.LevelInfo, "not inlined")
+	logger.Info("local")
+	testpackage.LogAttrs(logger, slog.LevelInfo, "attrs")
+
+	wantPackages := []string{"testpackage", "testpackage", "slog-env", "testpackage"}
+	for i, pc := range h.pcs {
+		f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		frame := callerFrame(pc)
+		assert.Equal(t, f.Function, frame.Function)
+		assert.Equal(t, f.File, frame.File)
+		assert.Equal(t, f.Line, frame.Line)
+
+		pkg, ok := parsePackage(frame.Function)
+		assert.True(t, ok)
+		assert.Equal(t, wantPackages[i], pkg, frame.Function)
+	}
+
+	assert.Equal(t, runtime.Frame{}, callerFrame(0))
+}
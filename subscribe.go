@@ -0,0 +1,69 @@
+package slogenv
+
+import "sync"
+
+// subscriberBuffer is the number of changes buffered for each subscriber before further changes are dropped.
+const subscriberBuffer = 16
+
+// ConfigChange is a runtime change to a handler's levels, as delivered by Subscribe.
+type ConfigChange struct {
+	// Old is the configuration before the change.
+	Old HandlerConfig
+	// New is the configuration after the change.
+	New HandlerConfig
+	// Diff describes the levels which changed.
+	Diff FilterDiff
+}
+
+// subscribers are the channels runtime changes to a handler's levels are sent to.
+type subscribers struct {
+	mu    sync.Mutex
+	chans map[chan ConfigChange]struct{}
+}
+
+// Subscribe returns a channel which receives every runtime change to the levels of the handler and every
+// handler derived from the same NewHandler call, such as by SetPackageLevel, UpdateLevels or a reloaded filter,
+// and a function which stops delivery and closes the channel. Updates which don't change any level aren't
+// delivered. Changes are buffered, and dropped rather than blocking the update if the buffer is full, so slow
+// receivers may miss changes but can always read the current levels from the handler.
+func (h *Handler) Subscribe() (<-chan ConfigChange, func()) {
+	ch := make(chan ConfigChange, subscriberBuffer)
+
+	s := h.subscribers
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.chans == nil {
+		s.chans = make(map[chan ConfigChange]struct{})
+	}
+	s.chans[ch] = struct{}{}
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.chans[ch]; ok {
+			delete(s.chans, ch)
+			close(ch)
+		}
+	}
+}
+
+// notify sends the change from the old to the new level state to every subscriber with room for it.
+func (s *subscribers) notify(old, new *levelState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.chans) == 0 {
+		return
+	}
+
+	diff := diffLevels(old.defaultLevel, old.perPackageLevel, new.defaultLevel, new.perPackageLevel)
+	if diff.Empty() {
+		return
+	}
+	change := ConfigChange{Old: handlerConfig(old), New: handlerConfig(new), Diff: diff}
+	for ch := range s.chans {
+		select {
+		case ch <- change:
+		default:
+		}
+	}
+}
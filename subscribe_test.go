@@ -0,0 +1,83 @@
+package slogenv_test
+
+import (
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestSubscribe tests that runtime level changes are delivered to subscribers until they unsubscribe.
+func TestSubscribe(t *testing.T) {
+	t.Setenv("GO_LOG", "info,db=warn")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler())
+	changes, unsubscribe := handler.Subscribe()
+
+	derived := handler.WithGroup("http").(*slogenv.Handler)
+	require.NoError(t, derived.SetPackageLevel("db", slog.LevelDebug))
+	assert.Equal(t, slogenv.ConfigChange{
+		Old: slogenv.HandlerConfig{DefaultLevel: slog.LevelInfo, PackageLevels: map[string]slog.Level{"db": slog.LevelWarn}},
+		New: slogenv.HandlerConfig{DefaultLevel: slog.LevelInfo, PackageLevels: map[string]slog.Level{"db": slog.LevelDebug}},
+		Diff: slogenv.FilterDiff{
+			OldDefault: slog.LevelInfo,
+			NewDefault: slog.LevelInfo,
+			Changed:    []slogenv.PackageLevelChange{{Package: "db", Old: slog.LevelWarn, New: slog.LevelDebug}},
+		},
+	}, <-changes)
+
+	// Updates which don't change anything aren't delivered.
+	require.NoError(t, handler.SetPackageLevel("db", slog.LevelDebug))
+	require.NoError(t, handler.SetDefaultLevel(slog.LevelWarn))
+	assert.Equal(t, slog.LevelWarn, (<-changes).New.DefaultLevel)
+
+	unsubscribe()
+	require.NoError(t, handler.SetDefaultLevel(slog.LevelError))
+	_, open := <-changes
+	assert.False(t, open)
+	unsubscribe()
+}
+
+// TestSubscribeSlowReceiver tests that updates don't block on subscribers which aren't receiving.
+func TestSubscribeSlowReceiver(t *testing.T) {
+	t.Setenv("GO_LOG", "info")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler())
+	changes, unsubscribe := handler.Subscribe()
+	defer unsubscribe()
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, handler.SetDefaultLevel(slog.Level(i%2)))
+	}
+	assert.NotEmpty(t, changes)
+	assert.Less(t, len(changes), 100)
+	assert.Equal(t, slog.Level(1), handler.DefaultLevel())
+}
+
+// TestSubscribeFilterFile tests that reloaded filter files are delivered to subscribers.
+func TestSubscribeFilterFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter")
+	replaceFile(t, path, "warn")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(),
+		slogenv.WithFilterFile(path),
+		slogenv.WithFilterFileInterval(time.Millisecond),
+	)
+	defer handler.Close()
+	changes, unsubscribe := handler.Subscribe()
+	defer unsubscribe()
+
+	replaceFile(t, path, "warn,db=debug")
+	select {
+	case change := <-changes:
+		assert.Equal(t, []slogenv.PackageLevel{{Package: "db", Level: slog.LevelDebug}}, change.Diff.Added)
+	case <-time.After(time.Second):
+		t.Fatal("reloaded filter file wasn't delivered")
+	}
+}
@@ -0,0 +1,146 @@
+package slogenv
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithSuppressionSummary counts the records dropped by the filter for each package and level, and every interval
+// sends a summary of them to the inner handler as info records, one for each package and level with dropped
+// records, such as "slog-env: suppressed 1423 debug records from api in last 60s". The counts since the last
+// summary are also sent by Close. If interval isn't positive, the summary is only sent by Close. Summaries stop
+// being sent every interval once Close is called, or the context from WithBaseContext is done.
+func WithSuppressionSummary(interval time.Duration) Opt {
+	return func(cfg *config) {
+		cfg.suppressionSummary = &interval
+	}
+}
+
+// suppressedKey identifies the records counted together in a suppression summary.
+type suppressedKey struct {
+	pkg   string
+	level slog.Level
+}
+
+// suppressionSummary counts dropped records and periodically sends a summary of them to the inner handler.
+type suppressionSummary struct {
+	// now returns the current time, for measuring the time covered by each summary.
+	now func() time.Time
+	// counts holds an *atomic.Int64 for each suppressedKey with dropped records.
+	counts sync.Map
+
+	// mu serializes summaries.
+	mu    sync.Mutex
+	since time.Time
+
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// newSuppressionSummary creates a suppression summary counting from now.
+func newSuppressionSummary(now func() time.Time) *suppressionSummary {
+	return &suppressionSummary{now: now, since: now()}
+}
+
+// count counts a dropped record at level from pkg.
+func (s *suppressionSummary) count(pkg string, level slog.Level) {
+	key := suppressedKey{pkg: pkg, level: level}
+	counter, ok := s.counts.Load(key)
+	if !ok {
+		counter, _ = s.counts.LoadOrStore(key, &atomic.Int64{})
+	}
+	counter.(*atomic.Int64).Add(1)
+}
+
+// summarizeEvery sends a summary to h's inner handler every interval until stop is called or h's base
+// context is done.
+func (s *suppressionSummary) summarizeEvery(h *Handler, interval time.Duration) {
+	s.done = make(chan struct{})
+	s.stopped = make(chan struct{})
+
+	go func() {
+		defer close(s.stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.summarize(h)
+			case <-s.done:
+				return
+			case <-h.cfg.baseContext.Done():
+				return
+			}
+		}
+	}()
+}
+
+// stop stops sending summaries every interval and waits for the goroutine sending them to exit.
+// It is safe to call more than once, and does nothing if summaries weren't sent every interval.
+func (s *suppressionSummary) stop() {
+	if s.done == nil {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+	<-s.stopped
+}
+
+// summarize sends a record to h's inner handler for each package and level with records dropped since
+// the last summary, sorted by package and level, and resets the counts.
+func (s *suppressionSummary) summarize(h *Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	elapsed := now.Sub(s.since)
+	s.since = now
+
+	type suppressed struct {
+		suppressedKey
+		n int64
+	}
+	var summary []suppressed
+	s.counts.Range(func(key, counter any) bool {
+		if n := counter.(*atomic.Int64).Swap(0); n > 0 {
+			summary = append(summary, suppressed{suppressedKey: key.(suppressedKey), n: n})
+		}
+		return true
+	})
+	if len(summary) == 0 {
+		return
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		if summary[i].pkg != summary[j].pkg {
+			return summary[i].pkg < summary[j].pkg
+		}
+		return summary[i].level < summary[j].level
+	})
+
+	ctx := context.Background()
+	inner := h.innerHandler()
+	if !inner.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+	for _, entry := range summary {
+		pkg := entry.pkg
+		if pkg == "" {
+			pkg = "unresolvable callers"
+		}
+		msg := fmt.Sprintf("slog-env: suppressed %d %s records from %s in last %.0fs",
+			entry.n, strings.ToLower(entry.level.String()), pkg, elapsed.Seconds())
+		record := slog.NewRecord(now, slog.LevelInfo, msg, 0)
+		record.AddAttrs(slog.String("package", entry.pkg), slog.Int64("suppressed", entry.n))
+		_ = inner.Handle(ctx, record)
+	}
+}
@@ -0,0 +1,76 @@
+package slogenv
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cbrewster/slog-env/internal/testpackage"
+)
+
+// recordsHandler keeps every record.
+type recordsHandler struct {
+	records []slog.Record
+}
+
+func (*recordsHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *recordsHandler) Handle(_ context.Context, record slog.Record) error {
+	h.records = append(h.records, record)
+	return nil
+}
+func (h *recordsHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *recordsHandler) WithGroup(string) slog.Handler      { return h }
+
+// messages returns the message of every record.
+func (h *recordsHandler) messages() []string {
+	var messages []string
+	for _, record := range h.records {
+		messages = append(messages, record.Message)
+	}
+	return messages
+}
+
+// TestSuppressionSummaryContent tests the summaries sent for dropped records, using a fake clock.
+func TestSuppressionSummaryContent(t *testing.T) {
+	t.Setenv("GO_LOG", "info,testpackage=error")
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	h := &recordsHandler{}
+	handler := NewHandler(h, WithSuppressionSummary(0))
+	handler.suppression = newSuppressionSummary(func() time.Time { return now })
+	logger := slog.New(handler)
+
+	for i := 0; i < 1423; i++ {
+		testpackage.LogSomething(logger, slog.LevelDebug, "debug")
+	}
+	testpackage.LogSomething(logger, slog.LevelWarn, "warn")
+	logger.Debug("debug")
+	assert.NoError(t, handler.Handle(context.Background(), slog.NewRecord(now, slog.LevelDebug, "no caller", 0)))
+
+	now = now.Add(time.Minute)
+	handler.suppression.summarize(handler)
+	assert.Equal(t, []string{
+		"slog-env: suppressed 1 debug records from unresolvable callers in last 60s",
+		"slog-env: suppressed 1 debug records from slog-env in last 60s",
+		"slog-env: suppressed 1423 debug records from testpackage in last 60s",
+		"slog-env: suppressed 1 warn records from testpackage in last 60s",
+	}, h.messages())
+	for _, record := range h.records {
+		assert.Equal(t, now, record.Time)
+		assert.Equal(t, slog.LevelInfo, record.Level)
+	}
+
+	// The counts are reset by each summary, so records are only summarized once.
+	h.records = nil
+	now = now.Add(30 * time.Second)
+	handler.suppression.summarize(handler)
+	assert.Empty(t, h.messages())
+
+	testpackage.LogSomething(logger, slog.LevelInfo, "info")
+	now = now.Add(30 * time.Second)
+	handler.suppression.summarize(handler)
+	assert.Equal(t, []string{"slog-env: suppressed 1 info records from testpackage in last 30s"}, h.messages())
+}
@@ -0,0 +1,72 @@
+package slogenv_test
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestSuppressionSummary tests that a summary of dropped records is sent to the inner handler on Close.
+func TestSuppressionSummary(t *testing.T) {
+	t.Setenv("GO_LOG", "info,testpackage=warn")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h, slogenv.WithSuppressionSummary(0))
+	logger := slog.New(handler)
+	for i := 0; i < 3; i++ {
+		testpackage.LogSomething(logger, slog.LevelDebug, "testpackage debug")
+		testpackage.LogSomething(logger, slog.LevelInfo, "testpackage info")
+		logger.Debug("debug")
+	}
+	logger.Info("info")
+	assert.Equal(t, []string{"info"}, h.Messages())
+
+	h.Reset()
+	require.NoError(t, handler.Close())
+	records := h.Records()
+	require.Len(t, records, 3)
+	assert.Regexp(t, `^slog-env: suppressed 3 debug records from slog-env_test in last \ds$`, records[0].Message)
+	assert.Regexp(t, `^slog-env: suppressed 3 debug records from testpackage in last \ds$`, records[1].Message)
+	assert.Regexp(t, `^slog-env: suppressed 3 info records from testpackage in last \ds$`, records[2].Message)
+	assert.Equal(t, slog.LevelInfo, records[2].Level)
+	assert.Equal(t, map[string]string{"package": "testpackage", "suppressed": "3"}, recordAttrs(records[2]))
+}
+
+// TestSuppressionSummaryInterval tests that summaries are sent every interval, without counting a dropped
+// record in more than one summary.
+func TestSuppressionSummaryInterval(t *testing.T) {
+	t.Setenv("GO_LOG", "info")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h, slogenv.WithSuppressionSummary(time.Millisecond))
+	defer handler.Close()
+	logger := slog.New(handler)
+
+	suppressed := func() int64 {
+		var n int64
+		for _, record := range h.Records() {
+			record.Attrs(func(attr slog.Attr) bool {
+				if attr.Key == "suppressed" {
+					n += attr.Value.Int64()
+				}
+				return true
+			})
+		}
+		return n
+	}
+
+	for i := 0; i < 5; i++ {
+		logger.Debug("debug")
+	}
+	require.Eventually(t, func() bool { return suppressed() == 5 }, time.Second, time.Millisecond)
+
+	logger.Debug("debug")
+	require.Eventually(t, func() bool { return suppressed() == 6 }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,81 @@
+package slogenv
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+const (
+	// funcPrefix marks filter keys which match the function a record was logged from.
+	funcPrefix = "func:"
+	// filePrefix marks filter keys which match the file a record was logged from.
+	filePrefix = "file:"
+)
+
+// targetFilter is a filter for the function or file a record was logged from.
+// A filter such as func:acme/api.HandleLogin matches the function by its full name, or the end of it
+// following a slash, so func:api.HandleLogin matches too. Methods are named like api.(*Server).HandleLogin.
+// A filter such as file:server.go matches the file by its path, or the end of it following a slash.
+type targetFilter struct {
+	key    string
+	target string
+	file   bool
+	level  slog.Level
+}
+
+// targetFilters returns the function and file filters in perPackageLevel, longest target first
+// so the most specific filter is checked first.
+func targetFilters(perPackageLevel map[string]slog.Level) []targetFilter {
+	var targets []targetFilter
+	for key, level := range perPackageLevel {
+		if target, ok := strings.CutPrefix(key, funcPrefix); ok {
+			targets = append(targets, targetFilter{key: key, target: target, level: level})
+		} else if target, ok := strings.CutPrefix(key, filePrefix); ok {
+			targets = append(targets, targetFilter{key: key, target: target, file: true, level: level})
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		if len(targets[i].target) != len(targets[j].target) {
+			return len(targets[i].target) > len(targets[j].target)
+		}
+		return targets[i].key < targets[j].key
+	})
+	return targets
+}
+
+// matches reports whether the filter matches the function or file of the caller.
+func (t targetFilter) matches(caller resolvedPackage) bool {
+	name := caller.function
+	if t.file {
+		name = caller.file
+	}
+	return name == t.target || strings.HasSuffix(name, "/"+t.target)
+}
+
+// mayMatchPackage reports whether the filter could match records logged from pkg, given by its name or import path.
+// A file filter without a directory could match a file in any package.
+func (t targetFilter) mayMatchPackage(pkg string) bool {
+	var targetPkg string
+	if t.file {
+		slash := strings.LastIndex(t.target, "/")
+		if slash < 0 {
+			return true
+		}
+		targetPkg = t.target[:slash]
+	} else {
+		slash := strings.LastIndex(t.target, "/") + 1
+		dot := strings.Index(t.target[slash:], ".")
+		if dot < 0 {
+			return false
+		}
+		targetPkg = t.target[:slash+dot]
+	}
+	return targetPkg == pkg || strings.HasSuffix(targetPkg, "/"+pkg) || strings.HasSuffix(pkg, "/"+targetPkg)
+}
+
+// isTargetKey reports whether a filter key is for a function or file rather than a package.
+func isTargetKey(key string) bool {
+	return strings.HasPrefix(key, funcPrefix) || strings.HasPrefix(key, filePrefix)
+}
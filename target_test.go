@@ -0,0 +1,61 @@
+package slogenv_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/internal/testpackage/nested"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestTargetFilters tests filters for the function or file a record was logged from.
+func TestTargetFilters(t *testing.T) {
+	for _, test := range []struct {
+		filter       string
+		wantMessages []string
+	}{
+		{
+			filter:       "info,func:testpackage.LogSomething=debug",
+			wantMessages: []string{"LogSomething debug"},
+		},
+		{
+			filter:       "info,func:github.com/cbrewster/slog-env/internal/testpackage.LogContext=debug",
+			wantMessages: []string{"LogContext debug"},
+		},
+		{
+			// Function filters take precedence over package filters.
+			filter:       "info,testpackage=error,func:testpackage.LogSomething=debug",
+			wantMessages: []string{"LogSomething debug"},
+		},
+		{
+			filter:       "info,file:nested.go=debug",
+			wantMessages: []string{"nested debug"},
+		},
+		{
+			filter:       "info,file:internal/testpackage/test.go=debug,nested=debug,file:nested/nested.go=error",
+			wantMessages: []string{"LogSomething debug", "LogContext debug"},
+		},
+		{
+			// Only whole path elements match.
+			filter:       "info,file:est.go=debug,func:ackage.LogSomething=debug",
+			wantMessages: nil,
+		},
+	} {
+		t.Run(test.filter, func(t *testing.T) {
+			t.Setenv("GO_LOG", test.filter)
+
+			h := slogenvtest.NewCaptureHandler()
+			logger := slog.New(slogenv.NewHandler(h))
+			testpackage.LogSomething(logger, slog.LevelDebug, "LogSomething debug")
+			testpackage.LogContext(context.Background(), logger, slog.LevelDebug, "LogContext debug")
+			nested.LogSomething(logger, slog.LevelDebug, "nested debug")
+
+			assert.Equal(t, test.wantMessages, h.Messages())
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package slogenv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// WithResolveTrace writes a line to out for every record explaining how its level was resolved: the package it
+// was logged from, the kind of filter which matched, such as exact, wildcard or default, the filter itself,
+// and whether the record was emitted. It is meant to be enabled temporarily to debug a filter:
+//
+//	slog-env: trace level=DEBUG msg="connecting" package=db match=exact rule=db=DEBUG threshold=DEBUG emitted=true
+func WithResolveTrace(out io.Writer) Opt {
+	return func(cfg *config) {
+		cfg.resolveTraceOut = out
+	}
+}
+
+// ResolutionTrace explains how the handler resolves the level for a record.
+type ResolutionTrace struct {
+	// Package is the package the record was logged from, if it could be determined.
+	Package string
+	// Considered lists the filters checked, in order of precedence, until one matched.
+	// Entries are group paths prefixed with group:, the matching attribute filter prefixed with attr:,
+	// package filters formatted in filter syntax, package names, and "default" or "unresolvable" when
+	// the default or unresolvable level was used, or "context" when the context carried a level override
+	// from WithLevelOverride.
+	Considered []string
+	// Rule is the filter which decided the threshold. It is empty if the default applied.
+	Rule string
+	// Threshold is the minimum level the record needs to be emitted.
+	Threshold slog.Level
+	// Emitted is true if the record would be passed on to the inner handler.
+	Emitted bool
+}
+
+// Trace resolves the level for record and explains how it was decided, without emitting the record.
+// Unlike Handle, the package is always resolved, even when it can't change the outcome.
+func (h *Handler) Trace(ctx context.Context, record slog.Record) ResolutionTrace {
+	trace, _ := h.trace(ctx, record)
+	return trace
+}
+
+// trace resolves the level for record like Trace, also returning the resolution.
+func (h *Handler) trace(ctx context.Context, record slog.Record) (ResolutionTrace, resolution) {
+	var trace ResolutionTrace
+	if level, ok := levelOverride(ctx); ok {
+		trace.Package, _, _ = h.resolvePackage(record.PC)
+		trace.Considered = []string{"context"}
+		trace.Threshold = level
+		trace.Emitted = record.Level >= level
+		return trace, resolution{level: level}
+	}
+
+	res := h.resolve(h.levels(), record, &trace)
+
+	trace.Package = res.pkg
+	if trace.Package == "" {
+		trace.Package, _, _ = h.resolvePackage(record.PC)
+	}
+	trace.Rule = res.rule()
+	trace.Threshold = res.level
+	trace.Emitted = record.Level >= res.level
+	return trace, res
+}
+
+// consider records that a filter was checked. It does nothing on a nil trace.
+func (t *ResolutionTrace) consider(filter string) {
+	if t != nil {
+		t.Considered = append(t.Considered, filter)
+	}
+}
+
+// resolveTracer writes a line explaining the resolution of each record.
+type resolveTracer struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// write writes the explanation for record, ignoring errors from out.
+func (t *resolveTracer) write(record slog.Record, trace ResolutionTrace, res resolution) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "slog-env: trace level=%s msg=%q", record.Level, record.Message)
+	if trace.Package != "" {
+		fmt.Fprintf(&b, " package=%s", trace.Package)
+	}
+	fmt.Fprintf(&b, " match=%s", matchKind(trace, res))
+	if trace.Rule != "" {
+		fmt.Fprintf(&b, " rule=%s", trace.Rule)
+	}
+	fmt.Fprintf(&b, " threshold=%s emitted=%t\n", formatLevel(trace.Threshold), trace.Emitted)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	io.WriteString(t.out, b.String())
+}
+
+// matchKind describes the kind of filter which decided the resolution.
+func matchKind(trace ResolutionTrace, res resolution) string {
+	key := res.filterPackage
+	switch {
+	case len(trace.Considered) == 1 && trace.Considered[0] == "context":
+		return "context"
+	case !res.matched() && len(trace.Considered) > 0 && trace.Considered[len(trace.Considered)-1] == "unresolvable":
+		return "unresolvable"
+	case !res.matched():
+		return "default"
+	case res.messagePrefix != "":
+		return "message-prefix"
+	case strings.HasPrefix(key, groupPrefix):
+		return "group"
+	case isAttrKey(key):
+		return "attr"
+	case strings.HasPrefix(key, funcPrefix):
+		return "func"
+	case strings.HasPrefix(key, filePrefix):
+		return "file"
+	case isGlobKey(key):
+		return "glob"
+	case key == res.pkg || key == res.path || key == strings.TrimSuffix(res.pkg, "_test"):
+		return "exact"
+	}
+	// Wildcards, including the ones WithHierarchicalPackages creates for every package filter.
+	return "wildcard"
+}
@@ -0,0 +1,128 @@
+package slogenv_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// recordHere creates a record whose PC is in the calling function.
+func recordHere(level slog.Level, message string) slog.Record {
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:])
+	return slog.NewRecord(time.Now(), level, message, pcs[0])
+}
+
+// TestTrace tests explaining the resolution of records matching different kinds of filters.
+func TestTrace(t *testing.T) {
+	os.Setenv("GO_LOG", "warn,testpackage=debug,group:http/*=error")
+	defer os.Unsetenv("GO_LOG")
+
+	h := slogenvtest.NewCaptureHandler()
+	handler := slogenv.NewHandler(h, slogenv.WithMessagePrefixFilter("slog-env_test", "slow", slog.LevelDebug))
+	ctx := context.Background()
+
+	for _, test := range []struct {
+		name      string
+		handler   slog.Handler
+		record    slog.Record
+		wantTrace slogenv.ResolutionTrace
+	}{
+		{
+			name:    "default",
+			handler: handler,
+			record:  recordHere(slog.LevelInfo, "info"),
+			wantTrace: slogenv.ResolutionTrace{
+				Package:    "slog-env_test",
+				Considered: []string{`slog-env_test "slow"=DEBUG`, "slog-env_test", "default"},
+				Threshold:  slog.LevelWarn,
+				Emitted:    false,
+			},
+		},
+		{
+			name:    "message prefix",
+			handler: handler,
+			record:  recordHere(slog.LevelDebug, "slow query"),
+			wantTrace: slogenv.ResolutionTrace{
+				Package:    "slog-env_test",
+				Considered: []string{`slog-env_test "slow"=DEBUG`},
+				Rule:       `slog-env_test "slow"=DEBUG`,
+				Threshold:  slog.LevelDebug,
+				Emitted:    true,
+			},
+		},
+		{
+			name:    "package",
+			handler: handler,
+			record:  testpackage.Record(slog.LevelInfo, "info"),
+			wantTrace: slogenv.ResolutionTrace{
+				Package:    "testpackage",
+				Considered: []string{"testpackage"},
+				Rule:       "testpackage=DEBUG",
+				Threshold:  slog.LevelDebug,
+				Emitted:    true,
+			},
+		},
+		{
+			name:    "group",
+			handler: handler.WithGroup("http").WithGroup("api"),
+			record:  testpackage.Record(slog.LevelWarn, "warn"),
+			wantTrace: slogenv.ResolutionTrace{
+				Package:    "testpackage",
+				Considered: []string{"group:http/api"},
+				Rule:       "group:http/*=ERROR",
+				Threshold:  slog.LevelError,
+				Emitted:    false,
+			},
+		},
+		{
+			name:    "unresolvable",
+			handler: handler,
+			record:  slog.NewRecord(time.Now(), slog.LevelWarn, "no pc", 0),
+			wantTrace: slogenv.ResolutionTrace{
+				Considered: []string{"unresolvable"},
+				Threshold:  slog.LevelWarn,
+				Emitted:    true,
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			trace := test.handler.(*slogenv.Handler).Trace(ctx, test.record)
+			assert.Equal(t, test.wantTrace, trace)
+		})
+	}
+
+	// Tracing never emits records.
+	assert.Empty(t, h.Messages())
+}
+
+// TestResolveTrace tests the line written for each record with WithResolveTrace.
+func TestResolveTrace(t *testing.T) {
+	t.Setenv("GO_LOG", "warn,testpackage=debug,acme/*=error")
+
+	var out strings.Builder
+	h := slogenvtest.NewCaptureHandler()
+	logger := slog.New(slogenv.NewHandler(h, slogenv.WithResolveTrace(&out)))
+
+	testpackage.LogSomething(logger, slog.LevelDebug, "matched")
+	logger.Info("fell through")
+	logger.InfoContext(slogenv.WithLevelOverride(context.Background(), slog.LevelDebug), "overridden")
+	assert.NoError(t, logger.Handler().Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelWarn, "no caller", 0)))
+
+	assert.Equal(t, []string{"matched", "overridden", "no caller"}, h.Messages())
+	assert.Equal(t, `slog-env: trace level=DEBUG msg="matched" package=testpackage match=exact rule=testpackage=DEBUG threshold=DEBUG emitted=true
+slog-env: trace level=INFO msg="fell through" package=slog-env_test match=default threshold=WARN emitted=false
+slog-env: trace level=INFO msg="overridden" package=slog-env_test match=context threshold=DEBUG emitted=true
+slog-env: trace level=WARN msg="no caller" match=unresolvable threshold=WARN emitted=true
+`, out.String())
+}
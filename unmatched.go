@@ -0,0 +1,41 @@
+package slogenv
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// WithUnmatchedFilterWarning writes a warning to out for each package filter which didn't match any record
+// by the time Close is called, to catch misspelled package names. Group filters are included.
+func WithUnmatchedFilterWarning(out io.Writer) Opt {
+	return func(cfg *config) {
+		cfg.unmatchedOut = out
+	}
+}
+
+// unmatchedFilters tracks which filters have matched a record.
+type unmatchedFilters struct {
+	out      io.Writer
+	seen     sync.Map
+	reported sync.Once
+}
+
+// match records that the filter with key matched a record.
+func (u *unmatchedFilters) match(key string) {
+	if _, ok := u.seen.Load(key); !ok {
+		u.seen.Store(key, struct{}{})
+	}
+}
+
+// report writes a warning for each filter in state which never matched. It only reports once.
+func (u *unmatchedFilters) report(state *levelState) {
+	u.reported.Do(func() {
+		for _, key := range sortedPackages(state.perPackageLevel) {
+			if _, ok := u.seen.Load(key); ok {
+				continue
+			}
+			fmt.Fprintf(u.out, "slog-env: filter %s never matched a record\n", formatRule(key, "", state.perPackageLevel[key]))
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package slogenv_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/internal/testpackage"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// TestUnmatchedFilterWarning tests that filters which never matched a record are reported on Close.
+func TestUnmatchedFilterWarning(t *testing.T) {
+	t.Setenv("GO_LOG", "info,testpackage=debug,acme/aip=debug,group:http=warn,group:grpc=warn,noisy=off")
+
+	var out bytes.Buffer
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(), slogenv.WithUnmatchedFilterWarning(&out))
+	logger := slog.New(handler)
+
+	// Records above the band still count as matching.
+	testpackage.LogSomething(logger, slog.LevelError, "error")
+	logger.WithGroup("http").Debug("dropped")
+	assert.Empty(t, out.String())
+
+	require.NoError(t, handler.Close())
+	assert.Equal(t, "slog-env: filter acme/aip=DEBUG never matched a record\n"+
+		"slog-env: filter group:grpc=WARN never matched a record\n"+
+		"slog-env: filter noisy=off never matched a record\n", out.String())
+
+	// Warnings are only written once.
+	require.NoError(t, handler.Close())
+	assert.Equal(t, 3, bytes.Count(out.Bytes(), []byte("\n")))
+}
@@ -0,0 +1,116 @@
+package slogenv
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultFilterFileInterval is how often the filter file is checked for changes by default.
+const defaultFilterFileInterval = time.Second
+
+// WithFilterFile reads the filter from the file at path, such as one updated by a sidecar, and reloads it
+// whenever the file changes. The file takes precedence over the environment variable, which is used along with
+// the default filter if the file can't be read when the handler is created.
+//
+// If the file later goes missing or contains an invalid filter, or one which isn't allowed, such as by
+// WithAllowRuntimeVerbosityIncrease, the previous levels are kept and a warning is written to stderr.
+// Changes are checked for once a second, see WithFilterFileInterval. Replace the file atomically, such as by
+// renaming a new file over it, so a partially written filter is never read. Call Close to stop watching
+// the file, or see WithBaseContext.
+func WithFilterFile(path string) Opt {
+	return func(cfg *config) {
+		cfg.filterFile = path
+	}
+}
+
+// WithFilterFileInterval sets how often the file from WithFilterFile is checked for changes.
+// If interval isn't positive, the default of once a second is used and NewHandlerWithError reports the problem.
+func WithFilterFileInterval(interval time.Duration) Opt {
+	return func(cfg *config) {
+		cfg.filterFileInterval = interval
+	}
+}
+
+// fileWatcher polls the filter file for changes.
+type fileWatcher struct {
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// watchFilterFile starts checking the file at path for changes every interval, reloading the filter when it does.
+func (h *Handler) watchFilterFile(path string, interval time.Duration) *fileWatcher {
+	w := &fileWatcher{
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	last, _ := os.ReadFile(path)
+	go func() {
+		defer close(w.stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		missing := false
+		for {
+			select {
+			case <-ticker.C:
+			case <-w.done:
+				return
+			case <-h.cfg.baseContext.Done():
+				return
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				// Only warn once while the file stays missing, such as while it is being replaced.
+				if !missing {
+					fmt.Fprintf(os.Stderr, "slog-env: keeping previous levels, can't read filter file: %v\n", err)
+				}
+				missing = true
+				continue
+			}
+			missing = false
+
+			if bytes.Equal(data, last) {
+				continue
+			}
+			last = data
+
+			if err := h.reloadFilter(string(data)); err != nil {
+				fmt.Fprintf(os.Stderr, "slog-env: keeping previous levels, filter in %s can't be applied: %v\n", path, err)
+			}
+		}
+	}()
+	return w
+}
+
+// stop stops watching the file and waits for the watcher to exit. It is safe to call more than once.
+func (w *fileWatcher) stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+	})
+	<-w.stopped
+}
+
+// reloadFilter replaces the levels with those from filter, for this handler and every handler derived
+// from the same NewHandler call. If the filter is invalid or not allowed, or the override environment variable
+// is set, the levels are left unchanged.
+func (h *Handler) reloadFilter(filter string) error {
+	if h.cfg.overrideFilter() != "" {
+		return nil
+	}
+
+	h.stateMu.Lock()
+	defer h.stateMu.Unlock()
+
+	state, err := h.cfg.parseLevels(filter)
+	if err != nil {
+		return err
+	}
+	return h.replaceLevels(state)
+}
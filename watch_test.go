@@ -0,0 +1,125 @@
+package slogenv_test
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	slogenv "github.com/cbrewster/slog-env"
+	"github.com/cbrewster/slog-env/slogenvtest"
+)
+
+// replaceFile atomically replaces the contents of the file at path, so the watcher never sees a partial write.
+func replaceFile(t *testing.T, path, contents string) {
+	t.Helper()
+
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte(contents), 0o644))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+// TestFilterFile tests that the filter is reloaded when the filter file changes.
+func TestFilterFile(t *testing.T) {
+	t.Setenv("GO_LOG", "error")
+
+	path := filepath.Join(t.TempDir(), "filter")
+	replaceFile(t, path, "warn,db=debug\n")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(),
+		slogenv.WithFilterFile(path),
+		slogenv.WithFilterFileInterval(time.Millisecond),
+	)
+	defer handler.Close()
+
+	// The file takes precedence over the environment variable.
+	assert.Equal(t, slog.LevelWarn, handler.DefaultLevel())
+	assert.Equal(t, map[string]slog.Level{"db": slog.LevelDebug}, handler.PackageLevels())
+
+	replaceFile(t, path, "info\ncache=error\n")
+	require.Eventually(t, func() bool {
+		return handler.DefaultLevel() == slog.LevelInfo
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, map[string]slog.Level{"cache": slog.LevelError}, handler.PackageLevels())
+
+	// Invalid filters and missing files keep the previous levels.
+	replaceFile(t, path, "loud")
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, os.Remove(path))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, slog.LevelInfo, handler.DefaultLevel())
+	assert.Equal(t, map[string]slog.Level{"cache": slog.LevelError}, handler.PackageLevels())
+
+	replaceFile(t, path, "debug")
+	require.Eventually(t, func() bool {
+		return handler.DefaultLevel() == slog.LevelDebug
+	}, time.Second, time.Millisecond)
+
+	// Changes after Close are ignored.
+	require.NoError(t, handler.Close())
+	replaceFile(t, path, "error")
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, slog.LevelDebug, handler.DefaultLevel())
+}
+
+// TestFilterFileMissing tests that the environment variable is used if the filter file doesn't exist at startup.
+func TestFilterFileMissing(t *testing.T) {
+	t.Setenv("GO_LOG", "error")
+
+	path := filepath.Join(t.TempDir(), "filter")
+	handler, err := slogenv.NewHandlerWithError(slogenvtest.NewCaptureHandler(),
+		slogenv.WithFilterFile(path),
+		slogenv.WithFilterFileInterval(time.Millisecond),
+	)
+	defer handler.Close()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Equal(t, slog.LevelError, handler.DefaultLevel())
+
+	replaceFile(t, path, "debug")
+	require.Eventually(t, func() bool {
+		return handler.DefaultLevel() == slog.LevelDebug
+	}, time.Second, time.Millisecond)
+}
+
+// TestFilterFileOverrideEnvVar tests that the override environment variable takes precedence over the filter file,
+// including changes to it.
+func TestFilterFileOverrideEnvVar(t *testing.T) {
+	t.Setenv("SLOG_FORCE", "error")
+
+	path := filepath.Join(t.TempDir(), "filter")
+	replaceFile(t, path, "debug")
+
+	handler := slogenv.NewHandler(slogenvtest.NewCaptureHandler(),
+		slogenv.WithFilterFile(path),
+		slogenv.WithFilterFileInterval(time.Millisecond),
+		slogenv.WithOverrideEnvVar("SLOG_FORCE"),
+	)
+	defer handler.Close()
+	assert.Equal(t, slog.LevelError, handler.DefaultLevel())
+
+	replaceFile(t, path, "info")
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, slog.LevelError, handler.DefaultLevel())
+}
+
+// TestFilterFileInvalidInterval tests that an interval which isn't positive is reported and replaced by the default.
+func TestFilterFileInvalidInterval(t *testing.T) {
+	t.Setenv("GO_LOG", "error")
+
+	path := filepath.Join(t.TempDir(), "filter")
+	replaceFile(t, path, "warn")
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		handler, err := slogenv.NewHandlerWithError(slogenvtest.NewCaptureHandler(),
+			slogenv.WithFilterFile(path),
+			slogenv.WithFilterFileInterval(interval),
+		)
+		assert.ErrorContains(t, err, "filter file interval")
+		assert.Equal(t, slog.LevelWarn, handler.DefaultLevel())
+		require.NoError(t, handler.Close())
+	}
+}